@@ -2,10 +2,16 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -18,6 +24,79 @@ func main() {
 	// Initialize queue
 	job.InitQueue()
 
+	// Initialize card back storage
+	backDir := os.Getenv("GRIMOIRE_BACK_DIR")
+	if backDir == "" {
+		backDir = filepath.Join("data", "backs")
+	}
+	if err := job.InitCardBacks(backDir); err != nil {
+		log.Fatal(err)
+	}
+
+	// Initialize the card image cache, 1 GB unless GRIMOIRE_IMAGE_CACHE_MB says otherwise
+	imageCacheDir := os.Getenv("GRIMOIRE_IMAGE_CACHE_DIR")
+	if imageCacheDir == "" {
+		imageCacheDir = filepath.Join("data", "images")
+	}
+	imageCacheMB := int64(1024)
+	if size := os.Getenv("GRIMOIRE_IMAGE_CACHE_MB"); size != "" {
+		var err error
+		if imageCacheMB, err = strconv.ParseInt(size, 10, 64); err != nil {
+			log.Fatalf("Invalid GRIMOIRE_IMAGE_CACHE_MB %q: %v", size, err)
+		}
+	}
+	if err := job.InitImageCache(imageCacheDir, imageCacheMB<<20); err != nil {
+		log.Fatal(err)
+	}
+
+	// Initialize the card data cache, revalidated after GRIMOIRE_CARD_CACHE_TTL (default 24h)
+	cardCacheDir := os.Getenv("GRIMOIRE_CARD_CACHE_DIR")
+	if cardCacheDir == "" {
+		cardCacheDir = filepath.Join("data", "cards")
+	}
+	cardCacheTTL := 24 * time.Hour
+	if ttl := os.Getenv("GRIMOIRE_CARD_CACHE_TTL"); ttl != "" {
+		var err error
+		if cardCacheTTL, err = time.ParseDuration(ttl); err != nil {
+			log.Fatalf("Invalid GRIMOIRE_CARD_CACHE_TTL %q: %v", ttl, err)
+		}
+	}
+	if err := job.InitCardCache(cardCacheDir, cardCacheTTL); err != nil {
+		log.Fatal(err)
+	}
+	if cardCacheTTL > 0 {
+		go func() {
+			for range time.Tick(cardCacheTTL) {
+				job.PruneCardCache()
+			}
+		}()
+	}
+
+	// Look cards up somewhere other than api.scryfall.com, such as cmd/fake-scryfall
+	scryfall := job.NewScryfall(job.DefaultScryfallURL, nil)
+	if scryfallURL := os.Getenv("GRIMOIRE_SCRYFALL_URL"); scryfallURL != "" {
+		scryfall = job.NewScryfall(scryfallURL, nil)
+		job.SetCardSource(scryfall)
+		log.Printf("Looking up cards at %s", scryfallURL)
+	}
+
+	// Look cards up in a bulk data file instead, reloading it on SIGHUP. Jobs keep the
+	// current cards until the new file has loaded, so a reload holds both in memory.
+	if bulkPath := os.Getenv("GRIMOIRE_BULK_DATA"); bulkPath != "" {
+		if err := loadBulkData(bulkPath, scryfall); err != nil {
+			log.Fatal(err)
+		}
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		go func() {
+			for range hup {
+				if err := loadBulkData(bulkPath, scryfall); err != nil {
+					log.Printf("Keeping the current bulk data: %v", err)
+				}
+			}
+		}()
+	}
+
 	app := fiber.New()
 
 	// Add middleware
@@ -42,36 +121,228 @@ func main() {
 	log.Fatal(app.Listen(":8081"))
 }
 
+// loadBulkData makes jobs look cards up in the bulk data file at path
+func loadBulkData(path string, images *job.Scryfall) error {
+	bulk, err := job.LoadBulkData(path, images)
+	if err != nil {
+		return err
+	}
+	job.SetCardSource(bulk)
+	log.Printf("Looking up cards in %s (%d cards)", path, bulk.Len())
+	return nil
+}
+
 // SetupRoutes configures all API routes
 func SetupRoutes(app *fiber.App) {
 	app.Post("/api/submit", handleSubmit)
+	app.Post("/api/validate", handleValidate)
+	app.Get("/api/layouts", handleGetLayouts)
+	app.Get("/api/backs", handleGetCardBacks)
+	app.Post("/api/backs", handleUploadCardBack)
+	app.Get("/api/backs/:id", handleGetCardBack)
+	app.Get("/api/admin/cache", handleGetCacheStats)
 	app.Get("/api/:id", handleGetJob)
 	app.Get("/api/:id/pdf", handleGetJobPDF)
 	app.Get("/api/jobs", handleGetAllJobs)
 }
 
 func handleSubmit(c *fiber.Ctx) error {
-	decklist := c.FormValue("Decklist")
-	if decklist == "" {
+	options, err := parseJobOptions(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	decklist, err := readDecklist(c, &options)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Decklist is required",
+			"error": err.Error(),
 		})
 	}
 
+	// An uploaded card back is stored so later jobs can pick it by ID
+	if _, err := c.FormFile("card_back"); err == nil {
+		if options.BackURL != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "card_back and back_url cannot be used together",
+			})
+		}
+		if options.BackID != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "card_back and back_id cannot be used together",
+			})
+		}
+		backID, err := saveUploadedCardBack(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		options.BackID = backID
+	}
+
 	// Create and enqueue job
-	jobInstance, err := job.CreateJob(decklist)
+	jobInstance, err := job.CreateJob(decklist, options)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to create job: " + err.Error(),
 		})
 	}
 
-	return c.JSON(fiber.Map{
+	response := fiber.Map{
 		"job_id": jobInstance.ID,
 		"status": "queued",
+	}
+	if options.BackID != "" {
+		response["back_id"] = options.BackID
+	}
+
+	return c.JSON(response)
+}
+
+// handleValidate parses and resolves a decklist like a submitted job would, without
+// fetching images or generating a PDF, so mistakes can be fixed before submitting
+func handleValidate(c *fiber.Ctx) error {
+	options, err := parseJobOptions(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	decklist, err := readDecklist(c, &options)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	validation, err := job.ValidateDecklist(decklist, options)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(validation)
+}
+
+// readDecklist returns the decklist pasted in the "Decklist" field or uploaded as "decklist_file"
+func readDecklist(c *fiber.Ctx, options *job.JobOptions) (string, error) {
+	decklist := c.FormValue("Decklist")
+	if _, err := c.FormFile("decklist_file"); err == nil {
+		if decklist != "" {
+			return "", fmt.Errorf("Decklist and decklist_file cannot be used together")
+		}
+		if decklist, err = readUploadedDecklist(c, options); err != nil {
+			return "", err
+		}
+	}
+	if decklist == "" {
+		return "", fmt.Errorf("Decklist is required")
+	}
+	return decklist, nil
+}
+
+// decklistFileFormats maps decklist file extensions to their format
+var decklistFileFormats = map[string]string{
+	".dek": "mtgo",
+	".cod": "cockatrice",
+	".csv": "csv",
+}
+
+// readUploadedDecklist returns the contents of the "decklist_file" upload. When no
+// format was given, it is picked from the file extension.
+func readUploadedDecklist(c *fiber.Ctx, options *job.JobOptions) (string, error) {
+	fileHeader, err := c.FormFile("decklist_file")
+	if err != nil {
+		return "", fmt.Errorf("decklist_file is required")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to read decklist_file: %w", err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read decklist_file: %w", err)
+	}
+
+	if options.Format == "" {
+		options.Format = decklistFileFormats[strings.ToLower(filepath.Ext(fileHeader.Filename))]
+	}
+	return string(data), nil
+}
+
+// saveUploadedCardBack stores the "card_back" file from the request and returns its ID
+func saveUploadedCardBack(c *fiber.Ctx) (string, error) {
+	fileHeader, err := c.FormFile("card_back")
+	if err != nil {
+		return "", fmt.Errorf("card_back file is required")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to read card_back: %w", err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read card_back: %w", err)
+	}
+
+	return job.SaveCardBack(data)
+}
+
+func handleGetCardBacks(c *fiber.Ctx) error {
+	ids, err := job.ListCardBacks()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"backs": ids,
+	})
+}
+
+func handleUploadCardBack(c *fiber.Ctx) error {
+	backID, err := saveUploadedCardBack(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"back_id": backID,
 	})
 }
 
+func handleGetCardBack(c *fiber.Ctx) error {
+	data, err := job.LoadCardBack(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	c.Set("Content-Type", http.DetectContentType(data))
+	return c.Send(data)
+}
+
+// handleGetCacheStats reports the size and hit counts of the card data and image caches
+func handleGetCacheStats(c *fiber.Ctx) error {
+	return c.JSON(job.GetCacheStats())
+}
+
+func handleGetLayouts(c *fiber.Ctx) error {
+	return c.JSON(job.LayoutPresets())
+}
+
 func handleGetJob(c *fiber.Ctx) error {
 	jobID := c.Params("id")
 	jobInstance, exists := job.GetJob(jobID)
@@ -91,6 +362,22 @@ func handleGetJob(c *fiber.Ctx) error {
 		response["error"] = err.Error()
 	}
 
+	if warnings := jobInstance.GetWarnings(); len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+
+	if cards := jobInstance.GetCards(); len(cards) > 0 {
+		response["cards"] = cards
+	}
+
+	if diagnostics := jobInstance.GetDiagnostics(); len(diagnostics) > 0 {
+		response["diagnostics"] = diagnostics
+	}
+
+	if imageCache := jobInstance.GetImageCache(); imageCache != nil {
+		response["image_cache"] = imageCache
+	}
+
 	return c.JSON(response)
 }
 
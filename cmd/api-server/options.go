@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"Grimoire/internal/model/job"
+)
+
+// parseJobOptions reads the optional job settings from the submit form
+func parseJobOptions(c *fiber.Ctx) (job.JobOptions, error) {
+	options := job.JobOptions{
+		Layout:    c.FormValue("layout"),
+		CropMarks: c.FormValue("crop_marks"),
+		Bleed:     c.FormValue("bleed"),
+		BackID:    c.FormValue("back_id"),
+		Format:    c.FormValue("format"),
+		Order:     c.FormValue("order"),
+		Ink:       c.FormValue("ink"),
+		Render:    c.FormValue("render"),
+		Printing:  c.FormValue("printing"),
+	}
+
+	if sets := c.FormValue("preferred_sets"); sets != "" {
+		options.PreferredSets = strings.Split(sets, ",")
+	}
+	if sections := c.FormValue("sections"); sections != "" {
+		options.Sections = strings.Split(sections, ",")
+	}
+
+	var err error
+	if mapping := c.FormValue("csv_columns"); mapping != "" {
+		if options.CSVColumns, err = job.ParseCSVColumns(mapping); err != nil {
+			return options, err
+		}
+	}
+	if options.Gutter, err = formFloat(c, "gutter"); err != nil {
+		return options, err
+	}
+	if options.BleedSize, err = formFloat(c, "bleed_size"); err != nil {
+		return options, err
+	}
+	if options.CutLines, err = formBool(c, "cut_lines"); err != nil {
+		return options, err
+	}
+
+	duplex, err := formBool(c, "duplex")
+	if err != nil {
+		return options, err
+	}
+	options.Duplex = duplex != nil && *duplex
+
+	registration, err := formBool(c, "registration")
+	if err != nil {
+		return options, err
+	}
+	options.Registration = registration != nil && *registration
+
+	squareCorners, err := formBool(c, "square_corners")
+	if err != nil {
+		return options, err
+	}
+	options.SquareCorners = squareCorners != nil && *squareCorners
+
+	strict, err := formBool(c, "strict")
+	if err != nil {
+		return options, err
+	}
+	options.Strict = strict != nil && *strict
+
+	related, err := formBool(c, "related")
+	if err != nil {
+		return options, err
+	}
+	options.Related = related != nil && *related
+
+	skipInvalid, err := formBool(c, "skip_invalid")
+	if err != nil {
+		return options, err
+	}
+	options.SkipInvalid = skipInvalid != nil && *skipInvalid
+
+	// Validate only accepts back URLs on the card source's image hosts
+	options.BackURL = c.FormValue("back_url")
+
+	if options.CropMarks != "" && options.CutLines != nil {
+		return options, fmt.Errorf("crop_marks and cut_lines cannot be used together")
+	}
+
+	if options.BackID != "" && options.BackURL != "" {
+		return options, fmt.Errorf("back_id and back_url cannot be used together")
+	}
+
+	if err := options.Validate(); err != nil {
+		return options, err
+	}
+
+	return options, nil
+}
+
+// formFloat returns the number in form field key, or nil if the field is empty
+func formFloat(c *fiber.Ctx, key string) (*float64, error) {
+	raw := c.FormValue(key)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q", key, raw)
+	}
+	return &value, nil
+}
+
+// formBool returns the boolean in form field key, or nil if the field is empty
+func formBool(c *fiber.Ctx, key string) (*bool, error) {
+	raw := c.FormValue(key)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q", key, raw)
+	}
+	return &value, nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"Grimoire/internal/model/job"
+)
+
+// bulk-refresh downloads a Scryfall bulk data file for offline card lookups. The
+// new file replaces the old one in a single rename, so an API server reading it
+// never sees a partial download. Send the API server SIGHUP to load it.
+func main() {
+	kind := flag.String("type", "default_cards", "bulk data type to download: default_cards or all_cards")
+	out := flag.String("out", "data/default-cards.json", "file to save the bulk data to")
+	scryfallURL := flag.String("scryfall", job.DefaultScryfallURL, "Scryfall API to download from")
+	flag.Parse()
+
+	if envURL := os.Getenv("GRIMOIRE_SCRYFALL_URL"); envURL != "" && *scryfallURL == job.DefaultScryfallURL {
+		*scryfallURL = envURL
+	}
+
+	if err := job.NewScryfall(*scryfallURL, nil).DownloadBulkData(*kind, *out); err != nil {
+		log.Fatal(err)
+	}
+}
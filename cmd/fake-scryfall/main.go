@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+
+	"Grimoire/internal/fakescryfall"
+)
+
+// fake-scryfall serves a stand-in Scryfall API for running Grimoire offline. Point
+// the API server at it with GRIMOIRE_SCRYFALL_URL=http://localhost:8082.
+func main() {
+	addr := flag.String("addr", ":8082", "address to listen on")
+	cardsFile := flag.String("cards", "", "JSON array of Scryfall card objects to serve instead of the built-in cards")
+	flag.Parse()
+
+	cards := fakescryfall.DefaultCards()
+	if *cardsFile != "" {
+		f, err := os.Open(*cardsFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cards, err = fakescryfall.LoadCards(f)
+		f.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	log.Printf("Fake Scryfall serving %d cards on %s", len(cards), *addr)
+	log.Fatal(http.ListenAndServe(*addr, fakescryfall.New(cards)))
+}
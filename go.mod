@@ -8,6 +8,7 @@ require (
 	github.com/golang-queue/queue v0.4.0
 	github.com/google/uuid v1.6.0
 	github.com/signintech/gopdf v0.33.0
+	golang.org/x/image v0.25.0
 )
 
 require (
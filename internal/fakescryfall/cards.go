@@ -0,0 +1,124 @@
+package fakescryfall
+
+// DefaultCards returns a small card pool covering the card layouts Grimoire handles:
+// several printings of a staple, basic lands, a transform card, a split card, a meld
+// pair, a card that makes tokens, and a non-English printing
+func DefaultCards() []Card {
+	return []Card{
+		{
+			ID: "a3f2ee9c-7b4a-4c5e-9a8b-9f1e3d2c1b01", OracleID: "6ad8011d-3471-4369-9d68-b264cc027487",
+			Name: "Sol Ring", Lang: "en", Set: "cmr", CollectorNumber: "472", Layout: "normal",
+			ReleasedAt: "2020-11-20", Prices: Prices{USD: "1.20"}, MTGOID: 85116,
+			ManaCost: "{1}", TypeLine: "Artifact", OracleText: "{T}: Add {C}{C}.",
+		},
+		{
+			ID: "a3f2ee9c-7b4a-4c5e-9a8b-9f1e3d2c1b02", OracleID: "6ad8011d-3471-4369-9d68-b264cc027487",
+			Name: "Sol Ring", Lang: "en", Set: "c21", CollectorNumber: "263", Layout: "normal",
+			ReleasedAt: "2021-04-23", Prices: Prices{USD: "0.95"},
+			ManaCost: "{1}", TypeLine: "Artifact", OracleText: "{T}: Add {C}{C}.",
+		},
+		{
+			ID: "a3f2ee9c-7b4a-4c5e-9a8b-9f1e3d2c1b03", OracleID: "6ad8011d-3471-4369-9d68-b264cc027487",
+			Name: "Sol Ring", Lang: "en", Set: "lea", CollectorNumber: "270", Layout: "normal",
+			ReleasedAt: "1993-08-05", Prices: Prices{USD: "3500.00"},
+			ManaCost: "{1}", TypeLine: "Artifact", OracleText: "{T}: Add {C}{C}.",
+		},
+		{
+			ID: "b1c4d7e2-5f3a-4b6c-8d9e-0a1b2c3d4e01", OracleID: "4457ed35-7c10-48c8-9776-456485fdf070",
+			Name: "Lightning Bolt", Lang: "en", Set: "2xm", CollectorNumber: "141", Layout: "normal",
+			ReleasedAt: "2020-08-07", Prices: Prices{USD: "1.50"}, MTGOID: 81024, MTGOFoilID: 81025,
+			ManaCost: "{R}", TypeLine: "Instant", OracleText: "Lightning Bolt deals 3 damage to any target.", Colors: []string{"R"},
+		},
+		{
+			ID: "b1c4d7e2-5f3a-4b6c-8d9e-0a1b2c3d4e02", OracleID: "4457ed35-7c10-48c8-9776-456485fdf070",
+			Name: "Lightning Bolt", Lang: "en", Set: "m11", CollectorNumber: "149", Layout: "normal",
+			ReleasedAt: "2010-07-16", Prices: Prices{USD: "2.10"},
+			ManaCost: "{R}", TypeLine: "Instant", OracleText: "Lightning Bolt deals 3 damage to any target.", Colors: []string{"R"},
+		},
+		{
+			ID: "b1c4d7e2-5f3a-4b6c-8d9e-0a1b2c3d4e03", OracleID: "4457ed35-7c10-48c8-9776-456485fdf070",
+			Name: "Lightning Bolt", Lang: "ja", Set: "2xm", CollectorNumber: "141", Layout: "normal",
+			ReleasedAt: "2020-08-07", Prices: Prices{USD: "4.00"},
+			ManaCost: "{R}", TypeLine: "Instant", OracleText: "Lightning Bolt deals 3 damage to any target.", Colors: []string{"R"},
+		},
+		{
+			ID: "c2d5e8f3-6a4b-4c7d-9e0f-1b2c3d4e5f01", OracleID: "b34bb2dc-c1af-4d77-b0b3-a0fb342a5fc6",
+			Name: "Forest", Lang: "en", Set: "iko", CollectorNumber: "274", Layout: "normal",
+			ReleasedAt: "2020-04-24", Prices: Prices{USD: "0.10"},
+			TypeLine: "Basic Land — Forest", OracleText: "({T}: Add {G}.)",
+		},
+		{
+			ID: "c2d5e8f3-6a4b-4c7d-9e0f-1b2c3d4e5f02", OracleID: "b2c6aa39-2d2a-459c-a555-fb48ba993373",
+			Name: "Island", Lang: "en", Set: "iko", CollectorNumber: "264", Layout: "normal",
+			ReleasedAt: "2020-04-24", Prices: Prices{USD: "0.10"},
+			TypeLine: "Basic Land — Island", OracleText: "({T}: Add {U}.)",
+		},
+		{
+			ID: "d3e6f9a4-7b5c-4d8e-0f1a-2c3d4e5f6a01", OracleID: "7e3ef4bb-3b15-4b09-9b23-e1b8b4b8c2a9",
+			Name: "Delver of Secrets // Insectile Aberration", Lang: "en", Set: "isd", CollectorNumber: "51", Layout: "transform",
+			ReleasedAt: "2011-09-30", Prices: Prices{USD: "0.75"},
+			CardFaces: []CardFace{
+				{Name: "Delver of Secrets", ManaCost: "{U}", TypeLine: "Creature — Human Wizard", OracleText: "At the beginning of your upkeep, look at the top card of your library. You may reveal that card. If an instant or sorcery card is revealed this way, transform Delver of Secrets.", Power: "1", Toughness: "1", Colors: []string{"U"}},
+				{Name: "Insectile Aberration", TypeLine: "Creature — Human Insect", OracleText: "Flying", Power: "3", Toughness: "2", Colors: []string{"U"}},
+			},
+		},
+		{
+			ID: "e4f7a0b5-8c6d-4e9f-1a2b-3d4e5f6a7b01", OracleID: "b3b0a3d1-4c56-4ba3-a3a1-3b0f6e0ac1f9",
+			Name: "Fire // Ice", Lang: "en", Set: "apc", CollectorNumber: "128", Layout: "split",
+			ReleasedAt: "2001-06-04", Prices: Prices{USD: "0.60"}, ManaCost: "{1}{R} // {1}{U}",
+			TypeLine: "Instant // Instant", Colors: []string{"R", "U"},
+			CardFaces: []CardFace{
+				{Name: "Fire", ManaCost: "{1}{R}", TypeLine: "Instant", OracleText: "Fire deals 2 damage divided as you choose among one or two targets."},
+				{Name: "Ice", ManaCost: "{1}{U}", TypeLine: "Instant", OracleText: "Tap target permanent.\nDraw a card."},
+			},
+		},
+		{
+			ID: "f5a8b1c6-9d7e-4f0a-2b3c-4e5f6a7b8c01", OracleID: "5a7a212e-e0b6-4f12-a95c-173cae023f93",
+			Name: "Bruna, the Fading Light", Lang: "en", Set: "emn", CollectorNumber: "15a", Layout: "meld",
+			ReleasedAt: "2016-07-22", Prices: Prices{USD: "0.50"},
+			ManaCost: "{5}{W}{W}", TypeLine: "Legendary Creature — Angel Horror", Power: "5", Toughness: "7", Colors: []string{"W"},
+			OracleText: "When you cast this spell, you may return target Angel or Human creature card from your graveyard to the battlefield.\nFlying, vigilance",
+			AllParts:   meldParts,
+		},
+		{
+			ID: "f5a8b1c6-9d7e-4f0a-2b3c-4e5f6a7b8c02", OracleID: "c75c035a-7da9-4b36-982d-fca8220b1797",
+			Name: "Gisela, the Broken Blade", Lang: "en", Set: "emn", CollectorNumber: "28a", Layout: "meld",
+			ReleasedAt: "2016-07-22", Prices: Prices{USD: "1.00"},
+			ManaCost: "{2}{W}{W}", TypeLine: "Legendary Creature — Angel Horror", Power: "4", Toughness: "3", Colors: []string{"W"},
+			OracleText: "Flying, first strike, lifelink",
+			AllParts:   meldParts,
+		},
+		{
+			ID: "f5a8b1c6-9d7e-4f0a-2b3c-4e5f6a7b8c03", OracleID: "1bd8e3a0-0ed5-4b59-a3a6-8f2a5e3a3e3f",
+			Name: "Brisela, Voice of Nightmares", Lang: "en", Set: "emn", CollectorNumber: "15b", Layout: "meld",
+			ReleasedAt: "2016-07-22",
+			TypeLine:   "Legendary Creature — Eldrazi Angel", Power: "9", Toughness: "10", Colors: []string{"W"},
+			OracleText: "Flying, first strike, vigilance, lifelink\nYour opponents can't cast spells with mana value 3 or less.",
+			AllParts:   meldParts,
+		},
+		{
+			ID: "a6b9c2d7-0e8f-4a1b-3c4d-5f6a7b8c9d01", OracleID: "f1bb7bb8-8a1b-4b6f-9b9b-5c3f5b2f2a11",
+			Name: "Smothering Tithe", Lang: "en", Set: "rna", CollectorNumber: "22", Layout: "normal",
+			ReleasedAt: "2019-01-25", Prices: Prices{USD: "20.00"},
+			ManaCost: "{3}{W}", TypeLine: "Enchantment", Colors: []string{"W"},
+			OracleText: "Whenever an opponent draws a card, that player may pay {2}. If the player doesn't, you create a Treasure token.",
+			AllParts: []RelatedCard{
+				{ID: "a6b9c2d7-0e8f-4a1b-3c4d-5f6a7b8c9d01", Component: "combo_piece", Name: "Smothering Tithe", TypeLine: "Enchantment"},
+				{ID: "a6b9c2d7-0e8f-4a1b-3c4d-5f6a7b8c9d02", Component: "token", Name: "Treasure", TypeLine: "Token Artifact — Treasure"},
+			},
+		},
+		{
+			ID: "a6b9c2d7-0e8f-4a1b-3c4d-5f6a7b8c9d02", OracleID: "4a8a2d54-3b3b-4b6e-9e6e-0b6a5f1f6c22",
+			Name: "Treasure", Lang: "en", Set: "trna", CollectorNumber: "1", Layout: "token",
+			ReleasedAt: "2019-01-25",
+			TypeLine:   "Token Artifact — Treasure", OracleText: "{T}, Sacrifice this artifact: Add one mana of any color.",
+		},
+	}
+}
+
+// meldParts is the all_parts list shared by the cards of the Bruna and Gisela meld pair
+var meldParts = []RelatedCard{
+	{ID: "f5a8b1c6-9d7e-4f0a-2b3c-4e5f6a7b8c01", Component: "meld_part", Name: "Bruna, the Fading Light", TypeLine: "Legendary Creature — Angel Horror"},
+	{ID: "f5a8b1c6-9d7e-4f0a-2b3c-4e5f6a7b8c02", Component: "meld_part", Name: "Gisela, the Broken Blade", TypeLine: "Legendary Creature — Angel Horror"},
+	{ID: "f5a8b1c6-9d7e-4f0a-2b3c-4e5f6a7b8c03", Component: "meld_result", Name: "Brisela, Voice of Nightmares", TypeLine: "Legendary Creature — Eldrazi Angel"},
+}
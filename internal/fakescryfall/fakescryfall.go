@@ -0,0 +1,472 @@
+// Package fakescryfall is an in-memory stand-in for the parts of the Scryfall API
+// Grimoire uses, so decklists can be resolved and printed without the internet in
+// tests and local development. Card images, and the generic card back, are plain
+// coloured placeholders.
+package fakescryfall
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Card is a Scryfall card object, limited to the fields Grimoire reads
+type Card struct {
+	ID              string        `json:"id"`
+	OracleID        string        `json:"oracle_id,omitempty"`
+	Name            string        `json:"name"`
+	Lang            string        `json:"lang"`
+	Set             string        `json:"set"`
+	CollectorNumber string        `json:"collector_number"`
+	Layout          string        `json:"layout"`
+	SetType         string        `json:"set_type,omitempty"`
+	Oversized       bool          `json:"oversized,omitempty"`
+	Games           []string      `json:"games,omitempty"`
+	ReleasedAt      string        `json:"released_at,omitempty"`
+	Prices          Prices        `json:"prices"`
+	MTGOID          int           `json:"mtgo_id,omitempty"`
+	MTGOFoilID      int           `json:"mtgo_foil_id,omitempty"`
+	ManaCost        string        `json:"mana_cost,omitempty"`
+	TypeLine        string        `json:"type_line,omitempty"`
+	OracleText      string        `json:"oracle_text,omitempty"`
+	Power           string        `json:"power,omitempty"`
+	Toughness       string        `json:"toughness,omitempty"`
+	Loyalty         string        `json:"loyalty,omitempty"`
+	Colors          []string      `json:"colors,omitempty"`
+	CardFaces       []CardFace    `json:"card_faces,omitempty"`
+	AllParts        []RelatedCard `json:"all_parts,omitempty"`
+}
+
+// Prices holds a card's market prices
+type Prices struct {
+	USD string `json:"usd,omitempty"`
+}
+
+// CardFace is one face of a multi-faced card
+type CardFace struct {
+	Name       string   `json:"name"`
+	ManaCost   string   `json:"mana_cost,omitempty"`
+	TypeLine   string   `json:"type_line,omitempty"`
+	OracleText string   `json:"oracle_text,omitempty"`
+	Power      string   `json:"power,omitempty"`
+	Toughness  string   `json:"toughness,omitempty"`
+	Loyalty    string   `json:"loyalty,omitempty"`
+	Colors     []string `json:"colors,omitempty"`
+}
+
+// RelatedCard is an entry in a card's all_parts list
+type RelatedCard struct {
+	ID        string `json:"id"`
+	Component string `json:"component"`
+	Name      string `json:"name"`
+	TypeLine  string `json:"type_line,omitempty"`
+}
+
+// identifier is a card identifier in a /cards/collection request
+type identifier struct {
+	ID              string `json:"id,omitempty"`
+	MTGOID          int    `json:"mtgo_id,omitempty"`
+	Name            string `json:"name,omitempty"`
+	Set             string `json:"set,omitempty"`
+	CollectorNumber string `json:"collector_number,omitempty"`
+}
+
+// Server serves a fixed list of cards through Scryfall's endpoints. Names are
+// matched by the first card that has them, so list each card's default printing first.
+type Server struct {
+	cards []Card
+	mux   *http.ServeMux
+}
+
+// New returns a fake Scryfall API serving cards
+func New(cards []Card) *Server {
+	s := &Server{cards: cards, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /cards/named", s.handleNamed)
+	s.mux.HandleFunc("GET /cards/autocomplete", s.handleAutocomplete)
+	s.mux.HandleFunc("GET /cards/search", s.handleSearch)
+	s.mux.HandleFunc("POST /cards/collection", s.handleCollection)
+	s.mux.HandleFunc("GET /cards/mtgo/{id}", s.handleMTGO)
+	s.mux.HandleFunc("GET /cards/{id}", s.handleID)
+	s.mux.HandleFunc("GET /cards/{set}/{number}", s.handlePrinting)
+	s.mux.HandleFunc("GET /cards/{set}/{number}/{lang}", s.handlePrinting)
+	s.mux.HandleFunc("GET /sets/{code}", s.handleSet)
+	s.mux.HandleFunc("GET /bulk-data", s.handleBulkData)
+	s.mux.HandleFunc("GET /bulk-data/cards.json", s.handleBulkFile)
+	s.mux.HandleFunc("GET /card-back.png", s.handleCardBack)
+	return s
+}
+
+// Start runs a fake Scryfall API serving cards on a local port. Pass its URL to
+// job.NewScryfall, and Close it when done.
+func Start(cards []Card) *httptest.Server {
+	return httptest.NewServer(New(cards))
+}
+
+// LoadCards reads a JSON array of Scryfall card objects, such as a bulk data file
+func LoadCards(r io.Reader) ([]Card, error) {
+	var cards []Card
+	if err := json.NewDecoder(r).Decode(&cards); err != nil {
+		return nil, fmt.Errorf("invalid card list: %w", err)
+	}
+	return cards, nil
+}
+
+// ServeHTTP serves the request, tagging JSON responses with an ETag and answering
+// If-None-Match GET requests for unchanged responses with 304 Not Modified. Other
+// methods whose If-None-Match matches fail with 412 Precondition Failed, as RFC 9110
+// requires.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, r)
+	for key, values := range rec.Header() {
+		w.Header()[key] = values
+	}
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes())
+		return
+	}
+
+	sum := sha256.Sum256(rec.Body.Bytes())
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusNotModified)
+		} else {
+			w.WriteHeader(http.StatusPreconditionFailed)
+		}
+		return
+	}
+	w.WriteHeader(rec.Code)
+	w.Write(rec.Body.Bytes())
+}
+
+func (s *Server) handleNamed(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	set := strings.ToLower(query.Get("set"))
+	if name := query.Get("exact"); name != "" {
+		s.writeCard(w, r, s.find(func(c *Card) bool { return matchesName(c, name) && (set == "" || c.Set == set) }))
+		return
+	}
+	s.writeCard(w, r, s.fuzzy(query.Get("fuzzy"), set))
+}
+
+func (s *Server) handleAutocomplete(w http.ResponseWriter, r *http.Request) {
+	prefix := normalize(r.URL.Query().Get("q"))
+	names := []string{}
+	seen := make(map[string]bool)
+	for _, c := range s.cards {
+		if prefix != "" && strings.HasPrefix(normalize(c.Name), prefix) && !seen[c.Name] && len(names) < 20 {
+			seen[c.Name] = true
+			names = append(names, c.Name)
+		}
+	}
+	writeJSON(w, map[string]any{"object": "catalog", "data": names})
+}
+
+var (
+	searchGroupRe = regexp.MustCompile(`\(([^)]*)\)`)
+	searchTermRe  = regexp.MustCompile(`(\w+):(\S+)`)
+)
+
+// handleSearch supports the queries Grimoire makes: "oracleid:X game:paper", an
+// optional "set:X" and an optional "(set:a or set:b)" group
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := query.Get("q")
+
+	var anyOf []string
+	for _, group := range searchGroupRe.FindAllStringSubmatch(q, -1) {
+		for _, term := range searchTermRe.FindAllStringSubmatch(group[1], -1) {
+			if term[1] == "set" {
+				anyOf = append(anyOf, strings.ToLower(term[2]))
+			}
+		}
+	}
+	terms := make(map[string]string)
+	for _, term := range searchTermRe.FindAllStringSubmatch(searchGroupRe.ReplaceAllString(q, ""), -1) {
+		terms[term[1]] = strings.ToLower(term[2])
+	}
+
+	var results []Card
+	for _, c := range s.cards {
+		if terms["oracleid"] != "" && c.OracleID != terms["oracleid"] {
+			continue
+		}
+		if terms["set"] != "" && c.Set != terms["set"] {
+			continue
+		}
+		if len(anyOf) > 0 && !slices.Contains(anyOf, c.Set) {
+			continue
+		}
+		results = append(results, c)
+	}
+	if len(results) == 0 {
+		writeError(w, http.StatusNotFound, "Your query didn’t match any cards.")
+		return
+	}
+
+	key := func(c Card) string { return c.ReleasedAt }
+	if query.Get("order") == "usd" {
+		key = func(c Card) string {
+			price, err := strconv.ParseFloat(c.Prices.USD, 64)
+			if err != nil {
+				return "~" // cards without a price sort last
+			}
+			return fmt.Sprintf("%012.2f", price)
+		}
+	}
+	desc := query.Get("dir") == "desc"
+	sort.SliceStable(results, func(i, j int) bool {
+		if desc {
+			return key(results[i]) > key(results[j])
+		}
+		return key(results[i]) < key(results[j])
+	})
+	writeJSON(w, map[string]any{"object": "list", "total_cards": len(results), "has_more": false, "data": results})
+}
+
+func (s *Server) handleCollection(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Identifiers []identifier `json:"identifiers"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || len(request.Identifiers) > 75 {
+		writeError(w, http.StatusBadRequest, "Invalid collection request.")
+		return
+	}
+
+	data := []Card{}
+	notFound := []identifier{}
+	for _, id := range request.Identifiers {
+		card := s.find(func(c *Card) bool {
+			switch {
+			case id.ID != "":
+				return c.ID == id.ID
+			case id.MTGOID != 0:
+				return c.MTGOID == id.MTGOID || c.MTGOFoilID == id.MTGOID
+			case id.CollectorNumber != "":
+				return c.Set == strings.ToLower(id.Set) && c.CollectorNumber == id.CollectorNumber && isEnglish(c)
+			}
+			return matchesName(c, id.Name) && (id.Set == "" || c.Set == strings.ToLower(id.Set))
+		})
+		if card == nil {
+			notFound = append(notFound, id)
+			continue
+		}
+		data = append(data, *card)
+	}
+	writeJSON(w, map[string]any{"object": "list", "not_found": notFound, "data": data})
+}
+
+func (s *Server) handleMTGO(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.PathValue("id"))
+	s.writeCard(w, r, s.find(func(c *Card) bool { return id != 0 && (c.MTGOID == id || c.MTGOFoilID == id) }))
+}
+
+func (s *Server) handleID(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	s.writeCard(w, r, s.find(func(c *Card) bool { return c.ID == id }))
+}
+
+func (s *Server) handlePrinting(w http.ResponseWriter, r *http.Request) {
+	set, number, lang := strings.ToLower(r.PathValue("set")), r.PathValue("number"), r.PathValue("lang")
+	s.writeCard(w, r, s.find(func(c *Card) bool {
+		if lang == "" || lang == "en" {
+			return c.Set == set && c.CollectorNumber == number && isEnglish(c)
+		}
+		return c.Set == set && c.CollectorNumber == number && c.Lang == lang
+	}))
+}
+
+func (s *Server) handleSet(w http.ResponseWriter, r *http.Request) {
+	code := strings.ToLower(r.PathValue("code"))
+	if s.find(func(c *Card) bool { return c.Set == code }) == nil {
+		writeError(w, http.StatusNotFound, "No set found for the given code.")
+		return
+	}
+	writeJSON(w, map[string]any{"object": "set", "code": code})
+}
+
+// handleBulkData lists every card as both the default_cards and all_cards bulk files
+func (s *Server) handleBulkData(w http.ResponseWriter, r *http.Request) {
+	uri := "http://" + r.Host + "/bulk-data/cards.json"
+	var files []map[string]any
+	for _, kind := range []string{"default_cards", "all_cards"} {
+		files = append(files, map[string]any{"object": "bulk_data", "type": kind, "download_uri": uri, "updated_at": "2024-01-01T00:00:00.000+00:00"})
+	}
+	writeJSON(w, map[string]any{"object": "list", "has_more": false, "data": files})
+}
+
+func (s *Server) handleBulkFile(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.cards)
+}
+
+// find returns the first card matching fn, or nil
+func (s *Server) find(fn func(c *Card) bool) *Card {
+	for i := range s.cards {
+		if fn(&s.cards[i]) {
+			return &s.cards[i]
+		}
+	}
+	return nil
+}
+
+// fuzzy finds a card the way Scryfall's fuzzy name search does, roughly: an exact
+// name, then a name starting with or containing the query, then a name a couple
+// of typos away
+func (s *Server) fuzzy(name, set string) *Card {
+	query := normalize(name)
+	if query == "" {
+		return nil
+	}
+	inSet := func(c *Card) bool { return set == "" || c.Set == set }
+	matchers := []func(c *Card) bool{
+		func(c *Card) bool { return matchesName(c, name) },
+		func(c *Card) bool { return strings.HasPrefix(normalize(c.Name), query) },
+		func(c *Card) bool { return strings.Contains(normalize(c.Name), query) },
+		func(c *Card) bool { return distance(normalize(c.Name), query) <= 2 },
+	}
+	for _, match := range matchers {
+		if card := s.find(func(c *Card) bool { return inSet(c) && match(c) }); card != nil {
+			return card
+		}
+	}
+	return nil
+}
+
+// writeCard writes card as JSON, or its image when the request asks for format=image
+func (s *Server) writeCard(w http.ResponseWriter, r *http.Request, card *Card) {
+	if card == nil {
+		writeError(w, http.StatusNotFound, "No card found matching the request.")
+		return
+	}
+	if r.URL.Query().Get("format") != "image" {
+		writeJSON(w, card)
+		return
+	}
+
+	colors := card.Colors
+	if r.URL.Query().Get("face") == "back" && len(card.CardFaces) > 1 {
+		colors = card.CardFaces[1].Colors
+	} else if len(colors) == 0 && len(card.CardFaces) > 0 {
+		colors = card.CardFaces[0].Colors
+	}
+	w.Header().Set("Content-Type", "image/png")
+	png.Encode(w, placeholderImage(colors))
+}
+
+// handleCardBack serves a placeholder for the generic card back, which job.NewScryfall
+// expects at /card-back.png on any API other than Scryfall's
+func (s *Server) handleCardBack(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "image/png")
+	png.Encode(w, borderedImage(cardBackColor))
+}
+
+// cardBackColor is the placeholder colour of the generic card back
+var cardBackColor = color.RGBA{110, 75, 45, 255}
+
+// frameColors are the placeholder frame colours for each colour of mana
+var frameColors = map[string]color.RGBA{
+	"W": {248, 231, 185, 255},
+	"U": {14, 104, 171, 255},
+	"B": {60, 50, 50, 255},
+	"R": {211, 32, 42, 255},
+	"G": {0, 115, 62, 255},
+}
+
+// placeholderImage returns a card-sized image in the colour of a card: gold for
+// multicoloured cards and grey for colourless ones, inside a black border
+func placeholderImage(colors []string) image.Image {
+	fill := color.RGBA{170, 170, 170, 255}
+	switch len(colors) {
+	case 0:
+	case 1:
+		fill = frameColors[colors[0]]
+	default:
+		fill = color.RGBA{212, 175, 55, 255}
+	}
+	return borderedImage(fill)
+}
+
+// borderedImage returns a card-sized image filled with fill inside a black border
+func borderedImage(fill color.Color) image.Image {
+	const width, height, border = 244, 340, 12
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x < border || y < border || x >= width-border || y >= height-border {
+				img.Set(x, y, color.Black)
+			} else {
+				img.Set(x, y, fill)
+			}
+		}
+	}
+	return img
+}
+
+// matchesName reports whether card is called name, or name is its front face
+func matchesName(card *Card, name string) bool {
+	name = normalize(name)
+	front, _, _ := strings.Cut(card.Name, " // ")
+	return name != "" && (normalize(card.Name) == name || normalize(front) == name)
+}
+
+func isEnglish(card *Card) bool {
+	return card.Lang == "" || card.Lang == "en"
+}
+
+// normalize lower-cases s and drops everything but letters, digits and slashes
+func normalize(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '/' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// distance returns the Levenshtein distance between a and b
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, details string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"object": "error", "status": status, "details": details})
+}
@@ -0,0 +1,103 @@
+package job
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Directory holding uploaded card back images
+var cardBackDir = filepath.Join("data", "backs")
+var cardBackMu sync.RWMutex
+
+// cardBackExts are the file extensions recognised as stored card backs
+var cardBackExts = []string{".jpg", ".jpeg", ".png"}
+
+// InitCardBacks sets the directory used to store card back images and creates it if needed
+func InitCardBacks(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create card back directory: %w", err)
+	}
+
+	cardBackMu.Lock()
+	defer cardBackMu.Unlock()
+	cardBackDir = dir
+	return nil
+}
+
+// SaveCardBack normalizes an uploaded card back image and stores it.
+// It returns the ID used to pick the back for later jobs.
+func SaveCardBack(imageData []byte) (string, error) {
+	converted, err := convertTo8Bit(imageData, imageOptions{})
+	if err != nil {
+		return "", fmt.Errorf("invalid card back image: %w", err)
+	}
+
+	sum := sha256.Sum256(converted)
+	id := hex.EncodeToString(sum[:8])
+
+	cardBackMu.Lock()
+	defer cardBackMu.Unlock()
+
+	path := filepath.Join(cardBackDir, id+".jpg")
+	if _, err := os.Stat(path); err == nil {
+		return id, nil // Same image already stored
+	}
+	if err := os.WriteFile(path, converted, 0o644); err != nil {
+		return "", fmt.Errorf("failed to store card back: %w", err)
+	}
+	return id, nil
+}
+
+// LoadCardBack returns the stored card back image with the given ID
+func LoadCardBack(id string) ([]byte, error) {
+	if id == "" || id != filepath.Base(id) || strings.HasPrefix(id, ".") {
+		return nil, fmt.Errorf("invalid card back id %q", id)
+	}
+
+	cardBackMu.RLock()
+	defer cardBackMu.RUnlock()
+
+	for _, ext := range cardBackExts {
+		data, err := os.ReadFile(filepath.Join(cardBackDir, id+ext))
+		if err == nil {
+			return data, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read card back %q: %w", id, err)
+		}
+	}
+	return nil, fmt.Errorf("card back %q not found", id)
+}
+
+// ListCardBacks returns the IDs of all stored card backs
+func ListCardBacks() ([]string, error) {
+	cardBackMu.RLock()
+	defer cardBackMu.RUnlock()
+
+	entries, err := os.ReadDir(cardBackDir)
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list card backs: %w", err)
+	}
+
+	ids := []string{}
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		for _, known := range cardBackExts {
+			if !entry.IsDir() && ext == known {
+				ids = append(ids, strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))
+				break
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
@@ -0,0 +1,447 @@
+package job
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// BulkData is a CardSource backed by a Scryfall bulk data file, such as
+// default-cards or all-cards, so cards are resolved without any API calls. Card
+// images are still downloaded through Images.
+type BulkData struct {
+	Path   string
+	Images *Scryfall
+
+	cards      []Card
+	byID       map[string]int
+	byPrinting map[string]int   // "set/number/lang"
+	byMTGO     map[int]int      // regular and foil MTGO catalog IDs
+	byName     map[string][]int // normalized full and front face names, English playable printings only
+	byOracle   map[string][]int // English playable printings only
+	names      []string         // the keys of byName, sorted, for fuzzy matching
+	sets       map[string]bool
+}
+
+// LoadBulkData reads and indexes the Scryfall bulk data file at path. A nil
+// images source gets the Scryfall API.
+func LoadBulkData(path string, images *Scryfall) (*BulkData, error) {
+	if images == nil {
+		images = NewScryfall(DefaultScryfallURL, nil)
+	}
+	b := &BulkData{
+		Path:       path,
+		Images:     images,
+		byID:       make(map[string]int),
+		byPrinting: make(map[string]int),
+		byMTGO:     make(map[int]int),
+		byName:     make(map[string][]int),
+		byOracle:   make(map[string][]int),
+		sets:       make(map[string]bool),
+	}
+	if err := readBulkFile(path, b.add); err != nil {
+		return nil, err
+	}
+	if len(b.cards) == 0 {
+		return nil, fmt.Errorf("bulk data file %s has no cards", path)
+	}
+
+	for name, indexes := range b.byName {
+		b.names = append(b.names, name)
+		// Name lookups pick the newest paper printing, like Scryfall's /cards/named
+		sort.SliceStable(indexes, func(i, j int) bool {
+			a, c := &b.cards[indexes[i]], &b.cards[indexes[j]]
+			if isPaper(a) != isPaper(c) {
+				return isPaper(a)
+			}
+			return a.ReleasedAt > c.ReleasedAt
+		})
+	}
+	sort.Strings(b.names)
+	return b, nil
+}
+
+// readBulkFile decodes the cards in a bulk data file one at a time, so the whole
+// file never has to be in memory
+func readBulkFile(path string, fn func(card Card)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open bulk data: %w", err)
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return fmt.Errorf("bulk data file %s is not a JSON array of cards", path)
+	}
+	for dec.More() {
+		var card Card
+		if err := dec.Decode(&card); err != nil {
+			return fmt.Errorf("invalid bulk data in %s: %w", path, err)
+		}
+		fn(card)
+	}
+	if _, err := dec.Token(); err != nil {
+		return fmt.Errorf("bulk data file %s is truncated: %w", path, err)
+	}
+	return nil
+}
+
+// add indexes a card read from the bulk data file
+func (b *BulkData) add(card Card) {
+	i := len(b.cards)
+	card.Set = strings.ToLower(card.Set)
+	if card.Lang == "" {
+		card.Lang = "en"
+	}
+	b.cards = append(b.cards, card)
+
+	b.byID[card.ID] = i
+	b.byPrinting[printingKey(card.Set, card.CollectorNumber, card.Lang)] = i
+	for _, mtgoID := range []int{card.MTGOID, card.MTGOFoilID} {
+		if mtgoID != 0 {
+			b.byMTGO[mtgoID] = i
+		}
+	}
+	b.sets[card.Set] = true
+	// Other printings stay reachable by ID and collector number only
+	if card.Lang != "en" || !isPlayable(&card) {
+		return
+	}
+	if card.OracleID != "" {
+		b.byOracle[card.OracleID] = append(b.byOracle[card.OracleID], i)
+	}
+	names := []string{normalizeName(card.Name)}
+	if front, _, ok := strings.Cut(card.Name, " // "); ok {
+		names = append(names, normalizeName(front))
+	}
+	for _, name := range names {
+		if name != "" {
+			b.byName[name] = append(b.byName[name], i)
+		}
+	}
+}
+
+// Len returns the number of cards in the bulk data
+func (b *BulkData) Len() int {
+	return len(b.cards)
+}
+
+// Card looks up a printing by Scryfall ID, MTGO catalog ID, set and collector
+// number (and language), or by fuzzy name within an optional set
+func (b *BulkData) Card(entry DeckEntry) (Card, error) {
+	i, ok := -1, false
+	switch {
+	case entry.ScryfallID != "":
+		i, ok = b.byID[strings.ToLower(entry.ScryfallID)]
+	case entry.MTGOID != 0:
+		i, ok = b.byMTGO[entry.MTGOID]
+	case entry.CollectorNumber == "":
+		i, ok = b.fuzzy(entry.Name, entry.Set)
+	default:
+		lang := entry.Language
+		if lang == "" {
+			lang = "en"
+		}
+		i, ok = b.byPrinting[printingKey(entry.Set, entry.CollectorNumber, lang)]
+	}
+	if !ok {
+		return Card{}, &APIError{StatusCode: http.StatusNotFound}
+	}
+	return b.cards[i], nil
+}
+
+// Collection looks each identifier up in the indexes
+func (b *BulkData) Collection(ids []CardIdentifier) ([]Card, []CardIdentifier, error) {
+	var found []Card
+	var notFound []CardIdentifier
+	for _, id := range ids {
+		i, ok := -1, false
+		switch {
+		case id.ID != "":
+			i, ok = b.byID[id.ID]
+		case id.MTGOID != 0:
+			i, ok = b.byMTGO[id.MTGOID]
+		case id.CollectorNumber != "":
+			i, ok = b.byPrinting[printingKey(id.Set, id.CollectorNumber, "en")]
+		default:
+			i, ok = b.named(normalizeName(id.Name), id.Set)
+		}
+		if !ok {
+			notFound = append(notFound, id)
+			continue
+		}
+		found = append(found, b.cards[i])
+	}
+	return found, notFound, nil
+}
+
+// Printings returns the English paper printings matching query
+func (b *BulkData) Printings(query PrintingQuery) ([]Card, error) {
+	var results []Card
+	for _, i := range b.byOracle[query.OracleID] {
+		card := b.cards[i]
+		if !isPaper(&card) {
+			continue
+		}
+		if query.Set != "" && card.Set != strings.ToLower(query.Set) {
+			continue
+		}
+		if len(query.Sets) > 0 && !slices.Contains(query.Sets, card.Set) {
+			continue
+		}
+		results = append(results, card)
+	}
+	if len(results) == 0 {
+		return nil, &APIError{StatusCode: http.StatusNotFound}
+	}
+
+	key := func(c *Card) string { return c.ReleasedAt }
+	if query.Order == "usd" {
+		key = func(c *Card) string {
+			price, err := strconv.ParseFloat(c.Prices.USD, 64)
+			if err != nil {
+				return "~" // cards without a price sort last
+			}
+			return fmt.Sprintf("%012.2f", price)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if query.Ascending {
+			return key(&results[i]) < key(&results[j])
+		}
+		return key(&results[i]) > key(&results[j])
+	})
+	return results, nil
+}
+
+// SetExists reports whether any card in the bulk data is from the set
+func (b *BulkData) SetExists(code string) (bool, error) {
+	return b.sets[strings.ToLower(code)], nil
+}
+
+// SuggestName returns the name of the card a fuzzy search for name finds
+func (b *BulkData) SuggestName(name string) (string, error) {
+	i, ok := b.fuzzy(name, "")
+	if !ok {
+		return "", nil
+	}
+	return b.cards[i].Name, nil
+}
+
+// ImageURIs points at the card's images on the Scryfall API
+func (b *BulkData) ImageURIs(card *Card) map[string]string {
+	return b.Images.ImageURIs(card)
+}
+
+// ImageHosts returns the hosts of the API images are downloaded from
+func (b *BulkData) ImageHosts() []string {
+	return b.Images.ImageHosts()
+}
+
+// BackURL returns the generic card back of the API images are downloaded from
+func (b *BulkData) BackURL() string {
+	return b.Images.BackURL()
+}
+
+// named returns the default printing of the card with the normalized name,
+// within set when given
+func (b *BulkData) named(name, set string) (int, bool) {
+	set = strings.ToLower(set)
+	for _, i := range b.byName[name] {
+		if set == "" || b.cards[i].Set == set {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// fuzzy finds a card roughly the way Scryfall's fuzzy name search does: an exact
+// name, then the only name starting with or containing the query, then the
+// closest name a couple of typos away
+func (b *BulkData) fuzzy(name, set string) (int, bool) {
+	query := normalizeName(name)
+	if query == "" {
+		return -1, false
+	}
+	if i, ok := b.named(query, set); ok {
+		return i, true
+	}
+
+	matchers := []func(name string) bool{
+		func(name string) bool { return strings.HasPrefix(name, query) },
+		func(name string) bool { return strings.Contains(name, query) },
+	}
+	for _, match := range matchers {
+		var matched []string
+		for _, name := range b.names {
+			if match(name) {
+				if _, ok := b.named(name, set); ok {
+					matched = append(matched, name)
+				}
+			}
+		}
+		// Several matches are ambiguous, as on Scryfall
+		if len(matched) == 1 {
+			return b.named(matched[0], set)
+		}
+		if len(matched) > 1 {
+			return -1, false
+		}
+	}
+
+	best, bestDistance := "", 3
+	for _, name := range b.names {
+		if abs(len(name)-len(query)) >= bestDistance {
+			continue
+		}
+		if d := levenshtein(name, query); d < bestDistance {
+			if _, ok := b.named(name, set); ok {
+				best, bestDistance = name, d
+			}
+		}
+	}
+	if best == "" {
+		return -1, false
+	}
+	return b.named(best, set)
+}
+
+// bulkDataList is the response to a /bulk-data request
+type bulkDataList struct {
+	Data []struct {
+		Type        string `json:"type"`
+		DownloadURI string `json:"download_uri"`
+		UpdatedAt   string `json:"updated_at"`
+	} `json:"data"`
+}
+
+// DownloadBulkData downloads the newest Scryfall bulk data file of the given type,
+// such as "default_cards" or "all_cards", and swaps it in at path. The download
+// goes to a temporary file next to path and is only renamed over it once it has
+// been read back completely, so path always holds a whole bulk data file.
+func (s *Scryfall) DownloadBulkData(kind, path string) error {
+	kind = strings.ReplaceAll(kind, "-", "_")
+	var list bulkDataList
+	if err := s.get(s.BaseURL+"/bulk-data", &list); err != nil {
+		return fmt.Errorf("failed to list bulk data: %w", err)
+	}
+	downloadURI := ""
+	for _, file := range list.Data {
+		if file.Type == kind {
+			downloadURI = file.DownloadURI
+			log.Printf("Downloading %s bulk data updated at %s", kind, file.UpdatedAt)
+			break
+		}
+	}
+	if downloadURI == "" {
+		return fmt.Errorf("unknown bulk data type %q", kind)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create bulk data directory: %w", err)
+	}
+	// Bulk files are hundreds of megabytes, so the download gets no overall timeout
+	client := &http.Client{Transport: s.Client.Transport}
+	resp, err := client.Get(downloadURI)
+	if err != nil {
+		return fmt.Errorf("failed to download bulk data: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download bulk data: %w", &APIError{StatusCode: resp.StatusCode})
+	}
+
+	count := 0
+	var checkErr error
+	err = replaceFile(filepath.Dir(path), filepath.Base(path), resp.Body, func(tmp string) error {
+		checkErr = readBulkFile(tmp, func(Card) { count++ })
+		if checkErr == nil && count == 0 {
+			checkErr = fmt.Errorf("downloaded bulk data has no cards")
+		}
+		return checkErr
+	})
+	if checkErr != nil {
+		return checkErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save bulk data to %s: %w", path, err)
+	}
+	log.Printf("Saved %d cards to %s", count, path)
+	return nil
+}
+
+// printingKey is the byPrinting index key of a printing
+func printingKey(set, number, lang string) string {
+	return strings.ToLower(set) + "/" + number + "/" + lang
+}
+
+// isPaper reports whether a card was printed on paper. Cards that don't list
+// their games are assumed to be.
+func isPaper(card *Card) bool {
+	return len(card.Games) == 0 || slices.Contains(card.Games, "paper")
+}
+
+// unplayableLayouts are the layouts of card-like objects that aren't cards
+var unplayableLayouts = map[string]bool{
+	"art_series": true,
+}
+
+// unplayableSetTypes are the types of sets whose printings aren't legal cards, such
+// as gold-bordered World Championship decks
+var unplayableSetTypes = map[string]bool{
+	"memorabilia": true,
+	"minigame":    true,
+}
+
+// isPlayable reports whether card can be picked by name or among a card's printings:
+// art series cards, memorabilia and oversized cards can't
+func isPlayable(card *Card) bool {
+	return !card.Oversized && !unplayableLayouts[card.Layout] && !unplayableSetTypes[card.SetType]
+}
+
+// normalizeName lower-cases a card name and drops everything but letters and digits
+func normalizeName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,211 @@
+package job
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"Grimoire/internal/fakescryfall"
+)
+
+// loadFakeBulkData writes cards to a bulk data file and loads it
+func loadFakeBulkData(t *testing.T, cards []fakescryfall.Card) *BulkData {
+	t.Helper()
+	data, err := json.Marshal(cards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "cards.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	bulk, err := LoadBulkData(path, NewScryfall("http://fake.invalid", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bulk
+}
+
+func TestBulkDataCard(t *testing.T) {
+	bulk := loadFakeBulkData(t, fakescryfall.DefaultCards())
+
+	tests := []struct {
+		name  string
+		entry DeckEntry
+		want  string // "set/number/lang", empty for not found
+	}{
+		{"printing", DeckEntry{Name: "Sol Ring", Set: "LEA", CollectorNumber: "270"}, "lea/270/en"},
+		{"language", DeckEntry{Name: "Lightning Bolt", Set: "2xm", CollectorNumber: "141", Language: "ja"}, "2xm/141/ja"},
+		{"newest printing by name", DeckEntry{Name: "Sol Ring"}, "c21/263/en"},
+		{"name within set", DeckEntry{Name: "sol ring", Set: "cmr"}, "cmr/472/en"},
+		{"misspelt name", DeckEntry{Name: "Lightnig Bolt"}, "2xm/141/en"},
+		{"front face name", DeckEntry{Name: "Delver of Secrets"}, "isd/51/en"},
+		{"Scryfall ID", DeckEntry{ScryfallID: "B1C4D7E2-5F3A-4B6C-8D9E-0A1B2C3D4E02"}, "m11/149/en"},
+		{"MTGO foil ID", DeckEntry{MTGOID: 81025}, "2xm/141/en"},
+		{"unknown printing", DeckEntry{Name: "Sol Ring", Set: "lea", CollectorNumber: "999"}, ""},
+		{"unknown name", DeckEntry{Name: "Black Lotus"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			card, err := bulk.Card(tt.entry)
+			if tt.want == "" {
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+					t.Errorf("got %s/%s, %v, want a 404 APIError", card.Set, card.CollectorNumber, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := card.Set + "/" + card.CollectorNumber + "/" + card.Lang; got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBulkDataCollection(t *testing.T) {
+	bulk := loadFakeBulkData(t, fakescryfall.DefaultCards())
+
+	ids := []CardIdentifier{
+		{Set: "lea", CollectorNumber: "270"},
+		{Name: "sol ring"},
+		{Name: "sol rnig"},
+		{MTGOID: 85116},
+		{Set: "zzz", CollectorNumber: "1"},
+		{Name: "fire"},
+	}
+	found, notFound, err := bulk.Collection(ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, card := range found {
+		got = append(got, card.Set+"/"+card.CollectorNumber)
+	}
+	if want := []string{"lea/270", "c21/263", "cmr/472", "apc/128"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("found %v, want %v in request order", got, want)
+	}
+	if want := []CardIdentifier{ids[2], ids[4]}; !reflect.DeepEqual(notFound, want) {
+		t.Errorf("not found %v, want %v", notFound, want)
+	}
+}
+
+func TestBulkDataPrintings(t *testing.T) {
+	bulk := loadFakeBulkData(t, fakescryfall.DefaultCards())
+	const solRing = "6ad8011d-3471-4369-9d68-b264cc027487"
+
+	tests := []struct {
+		query PrintingQuery
+		want  []string
+	}{
+		{PrintingQuery{OracleID: solRing, Order: "released"}, []string{"c21", "cmr", "lea"}},
+		{PrintingQuery{OracleID: solRing, Order: "released", Ascending: true}, []string{"lea", "cmr", "c21"}},
+		{PrintingQuery{OracleID: solRing, Order: "usd", Ascending: true}, []string{"c21", "cmr", "lea"}},
+		{PrintingQuery{OracleID: solRing, Order: "released", Sets: []string{"lea", "cmr"}}, []string{"cmr", "lea"}},
+		{PrintingQuery{OracleID: solRing, Order: "released", Set: "c21"}, []string{"c21"}},
+	}
+	for _, tt := range tests {
+		printings, err := bulk.Printings(tt.query)
+		if err != nil {
+			t.Fatalf("%+v: %v", tt.query, err)
+		}
+		var got []string
+		for _, card := range printings {
+			got = append(got, card.Set)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%+v: got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestBulkDataSetsAndNames(t *testing.T) {
+	bulk := loadFakeBulkData(t, fakescryfall.DefaultCards())
+
+	for code, want := range map[string]bool{"lea": true, "ISD": true, "zzz": false} {
+		if got, err := bulk.SetExists(code); got != want || err != nil {
+			t.Errorf("SetExists(%q) = %v, %v, want %v", code, got, err, want)
+		}
+	}
+	for name, want := range map[string]string{"Lightnig Bolt": "Lightning Bolt", "Sol Rign": "Sol Ring", "Qwertyuiop Zxcvb": ""} {
+		if got, err := bulk.SuggestName(name); got != want || err != nil {
+			t.Errorf("SuggestName(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+}
+
+func TestBulkDataSkipsUnplayable(t *testing.T) {
+	const solRing = "6ad8011d-3471-4369-9d68-b264cc027487"
+	cards := append(fakescryfall.DefaultCards(),
+		fakescryfall.Card{ID: "e1", OracleID: "e1-oracle", Name: "Sol Ring // Sol Ring", Lang: "en", Set: "acmr", CollectorNumber: "1", Layout: "art_series", SetType: "memorabilia", ReleasedAt: "2030-01-01"},
+		fakescryfall.Card{ID: "e2", OracleID: solRing, Name: "Sol Ring", Lang: "en", Set: "wc99", CollectorNumber: "1", Layout: "normal", SetType: "memorabilia", ReleasedAt: "2030-01-01", Prices: fakescryfall.Prices{USD: "0.01"}},
+		fakescryfall.Card{ID: "e3", OracleID: solRing, Name: "Sol Ring", Lang: "en", Set: "ocmr", CollectorNumber: "1", Layout: "normal", SetType: "promo", Oversized: true, ReleasedAt: "2031-01-01"},
+		fakescryfall.Card{ID: "e4", OracleID: solRing, Name: "Sol Ring", Lang: "en", Set: "cc1", CollectorNumber: "1", Layout: "normal", SetType: "commander", ReleasedAt: "2032-01-01", Games: []string{"mtgo"}},
+	)
+	bulk := loadFakeBulkData(t, cards)
+
+	if card, err := bulk.Card(DeckEntry{Name: "Sol Ring"}); err != nil || card.Set != "c21" {
+		t.Errorf("Sol Ring by name = %s, %v, want the c21 printing", card.Set, err)
+	}
+	if _, err := bulk.Card(DeckEntry{Name: "Sol Ring", Set: "wc99"}); err == nil {
+		t.Error("Sol Ring by name in wc99 found the memorabilia printing")
+	}
+	if card, err := bulk.Card(DeckEntry{Set: "wc99", CollectorNumber: "1"}); err != nil || card.ID != "e2" {
+		t.Errorf("wc99 1 = %s, %v, want the memorabilia printing", card.ID, err)
+	}
+	if card, err := bulk.Card(DeckEntry{ScryfallID: "e1"}); err != nil || card.Layout != "art_series" {
+		t.Errorf("e1 = %s, %v, want the art series card", card.ID, err)
+	}
+
+	printings, err := bulk.Printings(PrintingQuery{OracleID: solRing, Order: "usd", Ascending: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sets []string
+	for _, card := range printings {
+		sets = append(sets, card.Set)
+	}
+	if want := []string{"c21", "cmr", "lea"}; !reflect.DeepEqual(sets, want) {
+		t.Errorf("printings = %v, want %v", sets, want)
+	}
+}
+
+func TestDownloadBulkData(t *testing.T) {
+	srv, _ := useFakeScryfall(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cards.json")
+	source := NewScryfall(srv.URL, nil)
+
+	if err := source.DownloadBulkData("default-cards", path); err != nil {
+		t.Fatal(err)
+	}
+	bulk, err := LoadBulkData(path, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if card, err := bulk.Card(DeckEntry{Name: "Sol Ring", Set: "lea", CollectorNumber: "270"}); err != nil || card.Set != "lea" {
+		t.Errorf("lea 270 = %s, %v, want the downloaded card", card.Set, err)
+	}
+
+	// A download that fails its check leaves the previous file in place, with no
+	// temporary files
+	empty := httptest.NewServer(fakescryfall.New(nil))
+	defer empty.Close()
+	if err := NewScryfall(empty.URL, nil).DownloadBulkData("default_cards", path); err == nil {
+		t.Error("downloaded bulk data with no cards")
+	}
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 || files[0].Name() != "cards.json" {
+		t.Errorf("bulk data directory holds %v, want only cards.json", files)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o644 {
+		t.Errorf("cards.json mode = %v, %v, want 0644", info, err)
+	}
+}
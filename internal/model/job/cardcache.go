@@ -0,0 +1,293 @@
+package job
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cardCache keeps Scryfall card data on disk so repeated decklists resolve without
+// waiting on the API. It holds GET responses, keyed by URL, and the cards found by
+// collection lookups, keyed by Scryfall ID and by set and collector number. Entries
+// younger than ttl are used as they are. Older GET responses are revalidated with a
+// conditional request, using the ETag and Last-Modified headers Scryfall sent with
+// them; older cards are looked up again.
+type cardCache struct {
+	dir string
+	ttl time.Duration
+
+	mu    sync.Mutex
+	stats CardCacheStats
+}
+
+// cardCacheEntry is a cached API response or card
+type cardCacheEntry struct {
+	URL          string          `json:"url"` // the request URL of a response, the Scryfall ID of a card
+	ETag         string          `json:"etag,omitempty"`
+	LastModified string          `json:"last_modified,omitempty"`
+	FetchedAt    time.Time       `json:"fetched_at"` // when the response was last fetched or revalidated
+	Body         json.RawMessage `json:"body"`
+}
+
+// CardCacheStats describes the card metadata cache
+type CardCacheStats struct {
+	TTL         string `json:"ttl"`
+	Entries     int    `json:"entries"`
+	Bytes       int64  `json:"bytes"`
+	Hits        int    `json:"hits"`        // responses and cards used without asking Scryfall
+	Revalidated int    `json:"revalidated"` // expired responses Scryfall confirmed unchanged
+	Refreshed   int    `json:"refreshed"`   // expired responses and cards replaced by new ones
+	Misses      int    `json:"misses"`      // responses and cards with nothing cached
+}
+
+// ImageCacheUsage describes the card image cache
+type ImageCacheUsage struct {
+	Entries  int   `json:"entries"`
+	Bytes    int64 `json:"bytes"`
+	MaxBytes int64 `json:"max_bytes"`
+}
+
+// CacheStats describes the caches in use, nil for those that are disabled
+type CacheStats struct {
+	Cards  *CardCacheStats  `json:"cards"`
+	Images *ImageCacheUsage `json:"images"`
+}
+
+var (
+	cardMetadataCache *cardCache // nil when caching is disabled
+	cardCacheMu       sync.RWMutex
+)
+
+// InitCardCache stores Scryfall card data in dir, revalidating it once it is older
+// than ttl, and prunes the entries already there. A ttl of zero or less disables
+// the cache.
+func InitCardCache(dir string, ttl time.Duration) error {
+	var c *cardCache
+	if ttl > 0 {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create card cache directory: %w", err)
+		}
+		c = &cardCache{dir: dir, ttl: ttl}
+		c.prune()
+	}
+
+	cardCacheMu.Lock()
+	defer cardCacheMu.Unlock()
+	cardMetadataCache = c
+	return nil
+}
+
+func getCardCache() *cardCache {
+	cardCacheMu.RLock()
+	defer cardCacheMu.RUnlock()
+	return cardMetadataCache
+}
+
+// GetCacheStats returns the state of the card metadata and image caches
+func GetCacheStats() CacheStats {
+	var stats CacheStats
+	if c := getCardCache(); c != nil {
+		c.mu.Lock()
+		cardStats := c.stats
+		c.mu.Unlock()
+		cardStats.TTL = c.ttl.String()
+		cardStats.Entries, cardStats.Bytes = dirUsage(c.dir, ".json")
+		stats.Cards = &cardStats
+	}
+	if c := getImageCache(); c != nil {
+		c.mu.Lock()
+		stats.Images = &ImageCacheUsage{Entries: c.lru.Len(), Bytes: c.size, MaxBytes: c.maxBytes}
+		c.mu.Unlock()
+	}
+	return stats
+}
+
+// PruneCardCache deletes the card cache entries that are no longer worth keeping.
+// The API server runs it once per TTL.
+func PruneCardCache() {
+	getCardCache().prune()
+}
+
+// cacheKey returns the cache file name for the parts of a key
+func cacheKey(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(sum[:]) + ".json"
+}
+
+// responseCacheKey returns the cache file name for a GET request
+func responseCacheKey(apiURL string) string {
+	return cacheKey("GET", apiURL)
+}
+
+// identifierCacheKey returns the cache file name of the card a collection identifier
+// names. Names and MTGO IDs report false: cards are only cached by Scryfall ID and
+// by set and collector number, which always name the same printing.
+func identifierCacheKey(id CardIdentifier) (string, bool) {
+	switch {
+	case id.ID != "":
+		return cacheKey("card", strings.ToLower(id.ID)), true
+	case id.MTGOID == 0 && id.CollectorNumber != "":
+		return cacheKey("printing", strings.ToLower(id.Set), id.CollectorNumber), true
+	}
+	return "", false
+}
+
+// loadCard returns the card cached under key while it is fresh
+func (c *cardCache) loadCard(key string) (Card, bool) {
+	entry, ok := c.load(key)
+	if !ok || !c.fresh(entry) {
+		return Card{}, false
+	}
+	var card Card
+	if err := json.Unmarshal(entry.Body, &card); err != nil {
+		return Card{}, false
+	}
+	return card, true
+}
+
+// storeCard caches card under its Scryfall ID and its set and collector number
+func (c *cardCache) storeCard(card Card) {
+	if c == nil {
+		return
+	}
+	data, err := json.Marshal(card)
+	if err != nil {
+		log.Printf("Failed to cache %s: %v", card.Name, err)
+		return
+	}
+	// Cards found by name may be cached already; that's neither a miss nor a refresh
+	outcome := "miss"
+	for _, id := range []CardIdentifier{{ID: card.ID}, {Set: card.Set, CollectorNumber: card.CollectorNumber}} {
+		key, _ := identifierCacheKey(id)
+		if entry, ok := c.load(key); ok {
+			outcome = "refreshed"
+			if c.fresh(entry) {
+				outcome = ""
+			}
+		}
+		c.store(key, &cardCacheEntry{URL: card.ID, FetchedAt: time.Now(), Body: data})
+	}
+	c.count(outcome)
+}
+
+// load returns the cached response for key
+func (c *cardCache) load(key string) (*cardCacheEntry, bool) {
+	if c == nil {
+		return nil, false
+	}
+	data, err := os.ReadFile(filepath.Join(c.dir, key))
+	if err != nil {
+		return nil, false
+	}
+	var entry cardCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		log.Printf("Ignoring unreadable card cache entry %s: %v", key, err)
+		return nil, false
+	}
+	return &entry, true
+}
+
+// fresh reports whether entry can be used without revalidating it
+func (c *cardCache) fresh(entry *cardCacheEntry) bool {
+	return time.Since(entry.FetchedAt) < c.ttl
+}
+
+// store saves entry under key
+func (c *cardCache) store(key string, entry *cardCacheEntry) {
+	if c == nil {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("Failed to cache %s: %v", entry.URL, err)
+		return
+	}
+	if err := writeFileAtomic(c.dir, key, data); err != nil {
+		log.Printf("Failed to cache %s: %v", entry.URL, err)
+	}
+}
+
+// count records the outcome of a request in the cache stats: "hit",
+// "revalidated", "refreshed" or "miss"
+func (c *cardCache) count(outcome string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch outcome {
+	case "hit":
+		c.stats.Hits++
+	case "revalidated":
+		c.stats.Revalidated++
+	case "refreshed":
+		c.stats.Refreshed++
+	case "miss":
+		c.stats.Misses++
+	}
+}
+
+// prune deletes the entries that expired a full ttl ago, and expired cards, which
+// can't be revalidated. Expired responses are kept for a while so a conditional
+// request can still revalidate them.
+func (c *cardCache) prune() {
+	if c == nil {
+		return
+	}
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		log.Printf("Failed to prune the card cache: %v", err)
+		return
+	}
+	pruned := 0
+	for _, file := range files {
+		name := file.Name()
+		if strings.HasPrefix(name, ".tmp-") {
+			os.Remove(filepath.Join(c.dir, name)) // left by an interrupted write
+			continue
+		}
+		if file.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		entry, ok := c.load(name)
+		if ok {
+			age := time.Since(entry.FetchedAt)
+			revalidatable := entry.ETag != "" || entry.LastModified != ""
+			if age < c.ttl || (revalidatable && age < 2*c.ttl) {
+				continue
+			}
+		}
+		if err := os.Remove(filepath.Join(c.dir, name)); err == nil {
+			pruned++
+		}
+	}
+	if pruned > 0 {
+		log.Printf("Pruned %d expired entries from the card cache in %s", pruned, c.dir)
+	}
+}
+
+// dirUsage returns the number and total size of the files in dir with the extension
+func dirUsage(dir, ext string) (int, int64) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, 0
+	}
+	count, size := 0, int64(0)
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ext) {
+			continue
+		}
+		if info, err := file.Info(); err == nil {
+			count++
+			size += info.Size()
+		}
+	}
+	return count, size
+}
@@ -0,0 +1,192 @@
+package job
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// useCardCache caches card data in a temporary directory for the rest of the test
+func useCardCache(t *testing.T, ttl time.Duration) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := InitCardCache(dir, ttl); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { InitCardCache("", 0) })
+	return dir
+}
+
+// ageCardCache moves the fetch time of every entry in the card cache back by age
+func ageCardCache(t *testing.T, age time.Duration) {
+	t.Helper()
+	c := getCardCache()
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		entry, ok := c.load(file.Name())
+		if !ok {
+			t.Fatalf("unreadable cache entry %s", file.Name())
+		}
+		entry.FetchedAt = entry.FetchedAt.Add(-age)
+		c.store(file.Name(), entry)
+	}
+}
+
+func cardCacheStats() CardCacheStats {
+	stats := GetCacheStats().Cards
+	stats.TTL, stats.Entries, stats.Bytes = "", 0, 0
+	return *stats
+}
+
+func TestCardCacheResponses(t *testing.T) {
+	_, requests := useFakeScryfall(t)
+	useCardCache(t, time.Hour)
+	source := getCardSource()
+	entry := DeckEntry{Name: "Sol Ring", Set: "lea", CollectorNumber: "270"}
+
+	steps := []struct {
+		name     string
+		age      time.Duration // how far to age the cache before the lookup
+		requests int           // requests made to Scryfall so far
+		stats    CardCacheStats
+	}{
+		{"first lookup", 0, 1, CardCacheStats{Misses: 1}},
+		{"fresh", 0, 1, CardCacheStats{Hits: 1, Misses: 1}},
+		{"expired", 2 * time.Hour, 2, CardCacheStats{Hits: 1, Revalidated: 1, Misses: 1}},
+		{"revalidated", 0, 2, CardCacheStats{Hits: 2, Revalidated: 1, Misses: 1}},
+	}
+	for _, step := range steps {
+		ageCardCache(t, step.age)
+		card, err := source.Card(entry)
+		if err != nil || card.Set != "lea" || card.CollectorNumber != "270" {
+			t.Fatalf("%s: got %s/%s, %v", step.name, card.Set, card.CollectorNumber, err)
+		}
+		if n := requests.count("GET /cards/lea/270"); n != step.requests {
+			t.Errorf("%s: made %d requests, want %d", step.name, n, step.requests)
+		}
+		if stats := cardCacheStats(); stats != step.stats {
+			t.Errorf("%s: stats = %+v, want %+v", step.name, stats, step.stats)
+		}
+	}
+}
+
+func TestCardCacheCollection(t *testing.T) {
+	_, requests := useFakeScryfall(t)
+	useCardCache(t, time.Hour)
+
+	steps := []struct {
+		decklist    string
+		collections int // collection requests made so far
+		stats       CardCacheStats
+	}{
+		{"1 Sol Ring (lea) 270\n1 Sol Ring (c21) 263", 1, CardCacheStats{Misses: 2}},
+		// Cards are cached one by one, so a different decklist reuses them
+		{"1 Sol Ring (c21) 263\n1 Lightning Bolt (m11) 149", 2, CardCacheStats{Hits: 1, Misses: 3}},
+		{"2 Lightning Bolt (M11) 149\n1 Sol Ring (lea) 270", 2, CardCacheStats{Hits: 3, Misses: 3}},
+		// Names aren't cached, but finding a cached card by name doesn't count as a miss
+		{"1 Sol Ring\n1 Sol Ring (cmr) 472", 3, CardCacheStats{Hits: 3, Misses: 4}},
+	}
+	for _, step := range steps {
+		cards := fakeDeck(t, step.decklist)
+		if len(cards) != 2 {
+			t.Fatalf("%q resolved to %d cards", step.decklist, len(cards))
+		}
+		if n := requests.count("POST /cards/collection"); n != step.collections {
+			t.Errorf("%q: made %d collection requests, want %d", step.decklist, n, step.collections)
+		}
+		if stats := cardCacheStats(); stats != step.stats {
+			t.Errorf("%q: stats = %+v, want %+v", step.decklist, stats, step.stats)
+		}
+	}
+
+	// Cached cards expire like responses, and are looked up again
+	ageCardCache(t, 2*time.Hour)
+	fakeDeck(t, "1 Sol Ring (lea) 270")
+	if n := requests.count("POST /cards/collection"); n != 4 {
+		t.Errorf("made %d collection requests after the cache expired, want 4", n)
+	}
+	if stats := cardCacheStats(); stats.Refreshed != 1 {
+		t.Errorf("stats = %+v, want 1 refreshed", stats)
+	}
+}
+
+func TestFakeScryfallConditionalRequests(t *testing.T) {
+	srv, _ := useFakeScryfall(t)
+
+	resp, err := http.Get(srv.URL + "/cards/lea/270")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	etag := resp.Header.Get("ETag")
+
+	body := `{"identifiers":[{"set":"lea","collector_number":"270"}]}`
+	resp, err = http.Post(srv.URL+"/cards/collection", "application/json", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	postETag := resp.Header.Get("ETag")
+
+	tests := []struct {
+		method, path, body, etag string
+		want                     int
+	}{
+		{http.MethodGet, "/cards/lea/270", "", etag, http.StatusNotModified},
+		{http.MethodGet, "/cards/lea/270", "", `"stale"`, http.StatusOK},
+		{http.MethodPost, "/cards/collection", body, postETag, http.StatusPreconditionFailed},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, srv.URL+tt.path, bytes.NewBufferString(tt.body))
+		req.Header.Set("If-None-Match", tt.etag)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s %s with If-None-Match %s: status %d, want %d", tt.method, tt.path, tt.etag, resp.StatusCode, tt.want)
+		}
+	}
+}
+
+func TestCardCachePrune(t *testing.T) {
+	dir := useCardCache(t, time.Hour)
+	c := getCardCache()
+	now := time.Now()
+
+	entries := map[string]*cardCacheEntry{
+		"fresh.json":                 {FetchedAt: now.Add(-30 * time.Minute)},
+		"expired-card.json":          {FetchedAt: now.Add(-90 * time.Minute)},
+		"expired-response.json":      {ETag: `"x"`, FetchedAt: now.Add(-90 * time.Minute)},
+		"long-expired-response.json": {LastModified: "Mon, 01 Jan 2024 00:00:00 GMT", FetchedAt: now.Add(-3 * time.Hour)},
+	}
+	for name, entry := range entries {
+		entry.Body = []byte("{}")
+		c.store(name, entry)
+	}
+	os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0o644)
+	os.WriteFile(filepath.Join(dir, ".tmp-123"), []byte("{"), 0o644)
+
+	PruneCardCache()
+
+	for name, kept := range map[string]bool{
+		"fresh.json":                 true,
+		"expired-card.json":          false,
+		"expired-response.json":      true,
+		"long-expired-response.json": false,
+		"broken.json":                false,
+		".tmp-123":                   false,
+	} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if exists := err == nil; exists != kept {
+			t.Errorf("%s: exists = %v, want %v", name, exists, kept)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package job
+
+import (
+	"log"
+	"net/http"
+	"strings"
+)
+
+// collectionBatchSize is the most identifiers Scryfall's /cards/collection accepts per request
+const collectionBatchSize = 75
+
+// lookupResult is the outcome of looking up one decklist entry
+type lookupResult struct {
+	card Card
+	err  error
+}
+
+// entryIdentifier returns the collection identifier for entry. Non-English printings
+// can't be looked up through the collection, so they report false.
+func entryIdentifier(entry DeckEntry) (CardIdentifier, bool) {
+	switch {
+	case entry.ScryfallID != "":
+		return CardIdentifier{ID: strings.ToLower(entry.ScryfallID)}, true
+	case entry.MTGOID != 0:
+		return CardIdentifier{MTGOID: entry.MTGOID}, true
+	case entry.Language != "" && entry.Language != "en":
+		return CardIdentifier{}, false
+	case entry.CollectorNumber != "":
+		return CardIdentifier{Set: strings.ToLower(entry.Set), CollectorNumber: entry.CollectorNumber}, true
+	}
+	return CardIdentifier{Name: strings.ToLower(entry.Name), Set: strings.ToLower(entry.Set)}, true
+}
+
+// normalizeIdentifier returns id as entryIdentifier builds it, so identifiers Scryfall
+// echoes back compare equal to the ones sent
+func normalizeIdentifier(id CardIdentifier) CardIdentifier {
+	id.ID, id.Name, id.Set = strings.ToLower(id.ID), strings.ToLower(id.Name), strings.ToLower(id.Set)
+	return id
+}
+
+// cachedCard returns the fresh cached card the identifier names, counting it as a
+// cache hit
+func cachedCard(cache *cardCache, id CardIdentifier) (Card, bool) {
+	key, ok := identifierCacheKey(id)
+	if !ok {
+		return Card{}, false
+	}
+	card, ok := cache.loadCard(key)
+	if ok {
+		cache.count("hit")
+	}
+	return card, ok
+}
+
+// collectCards looks entries up in batches with the source's Collection lookup,
+// storing what it finds in results. Identifiers Scryfall doesn't know are stored
+// as not found, except names, which may be misspelt, and entries that fall back to
+// their name. It returns the indexes of the entries that still need looking up one
+// by one: misspelt names, name fallbacks, non-English printings and entries whose
+// batch failed. Cards in the card cache are used
+// without asking the source.
+func collectCards(label string, entries []DeckEntry, results []lookupResult, source CardSource, pref PrintingPreference) []int {
+	// Bulk data is already on disk, so only API lookups go through the card cache
+	var cache *cardCache
+	if _, ok := source.(*Scryfall); ok {
+		cache = getCardCache()
+	}
+	resolve := func(card Card, indexes []int) {
+		for _, i := range indexes {
+			results[i] = lookupResult{card: card}
+			results[i].err = completeCard(&results[i].card, entries[i], pref, source)
+		}
+	}
+
+	var pending []int
+	lines := make(map[CardIdentifier][]int)
+	cached := make(map[CardIdentifier]Card)
+	var identifiers []CardIdentifier
+	for i, entry := range entries {
+		id, ok := entryIdentifier(entry)
+		if !ok {
+			pending = append(pending, i)
+			continue
+		}
+		if _, seen := lines[id]; !seen {
+			if card, ok := cachedCard(cache, id); ok {
+				cached[id] = card
+			} else {
+				identifiers = append(identifiers, id)
+			}
+		}
+		lines[id] = append(lines[id], i)
+	}
+	for id, card := range cached {
+		resolve(card, lines[id])
+	}
+	if len(cached) > 0 {
+		log.Printf("%s: Found %d cards in the card cache", label, len(cached))
+	}
+
+	for start := 0; start < len(identifiers); start += collectionBatchSize {
+		batch := identifiers[start:min(start+collectionBatchSize, len(identifiers))]
+
+		found, notFound, err := source.Collection(batch)
+		if err != nil {
+			log.Printf("%s: Collection lookup of %d cards failed, looking them up one by one: %v", label, len(batch), err)
+			for _, id := range batch {
+				pending = append(pending, lines[id]...)
+			}
+			continue
+		}
+		log.Printf("%s: Collection lookup found %d of %d cards", label, len(found), len(batch))
+
+		// Scryfall answers in request order, leaving out the identifiers it lists as
+		// not found, so the remaining identifiers pair up with the cards by position
+		missing := make(map[CardIdentifier]bool, len(notFound))
+		for _, id := range notFound {
+			missing[normalizeIdentifier(id)] = true
+		}
+		if len(batch)-len(missing) != len(found) {
+			log.Printf("%s: Collection lookup returned %d cards for %d identifiers, looking them up one by one", label, len(found), len(batch)-len(missing))
+			for _, id := range batch {
+				pending = append(pending, lines[id]...)
+			}
+			continue
+		}
+
+		k := 0
+		for _, id := range batch {
+			indexes := lines[id]
+			if missing[normalizeIdentifier(id)] {
+				for _, i := range indexes {
+					// Try a fuzzy match for names, and the name of entries that fall
+					// back to it
+					if _, ok := entries[i].byName(); id.Name != "" || ok {
+						pending = append(pending, i)
+						continue
+					}
+					results[i] = lookupResult{err: &APIError{StatusCode: http.StatusNotFound}}
+				}
+				continue
+			}
+
+			card := found[k]
+			k++
+			cache.storeCard(card)
+			resolve(card, indexes)
+		}
+	}
+	return pending
+}
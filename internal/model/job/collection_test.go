@@ -0,0 +1,202 @@
+package job
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"Grimoire/internal/fakescryfall"
+)
+
+// requestLog counts the requests made to a fake Scryfall API by method and path
+type requestLog struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (l *requestLog) count(key string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.counts[key]
+}
+
+// useFakeScryfall looks cards up in a fake Scryfall API serving the default cards
+// for the rest of the test
+func useFakeScryfall(t *testing.T) (*httptest.Server, *requestLog) {
+	t.Helper()
+	fake := fakescryfall.New(fakescryfall.DefaultCards())
+	requests := &requestLog{counts: map[string]int{}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.mu.Lock()
+		requests.counts[r.Method+" "+r.URL.Path]++
+		requests.mu.Unlock()
+		fake.ServeHTTP(w, r)
+	}))
+
+	previous := getCardSource()
+	SetCardSource(NewScryfall(srv.URL, nil))
+	t.Cleanup(func() {
+		SetCardSource(previous)
+		srv.Close()
+	})
+	return srv, requests
+}
+
+func TestLookupCards(t *testing.T) {
+	tests := []struct {
+		name     string
+		decklist string
+		want     []string // "line: set/number" for cards, "line: category" for diagnostics
+	}{
+		{
+			name:     "printing before name",
+			decklist: "1 Sol Ring (lea) 270\n1 Sol Ring",
+			want:     []string{"1: lea/270", "2: cmr/472"},
+		},
+		{
+			name:     "name before printing",
+			decklist: "1 Sol Ring\n1 Sol Ring (lea) 270",
+			want:     []string{"1: cmr/472", "2: lea/270"},
+		},
+		{
+			name:     "repeated lines",
+			decklist: "1 Lightning Bolt (m11) 149\n1 Lightning Bolt\n2 Lightning Bolt (m11) 149",
+			want:     []string{"1: m11/149", "2: 2xm/141", "3: m11/149"},
+		},
+		{
+			name:     "not found between found cards",
+			decklist: "1 Forest (iko) 274\n1 Sol Ring (c21) 999\n1 Island (iko) 264\n1 Sol Rnig\n1 Fire // Ice",
+			want:     []string{"1: iko/274", "2: not_found", "3: iko/264", "4: cmr/472", "5: apc/128"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, requests := useFakeScryfall(t)
+			entries, errs := archidektParser{}.Parse(tt.decklist, ParseOptions{})
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+
+			_, resolved, diagnostics := lookupCards("Test", entries, PrintingPreference{})
+			got := make([]string, len(entries))
+			for _, card := range resolved {
+				got[card.Line-1] = fmt.Sprintf("%d: %s/%s", card.Line, card.Set, card.CollectorNumber)
+			}
+			for _, diag := range diagnostics {
+				got[diag.Line-1] = fmt.Sprintf("%d: %s", diag.Line, diag.Category)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if n := requests.count("POST /cards/collection"); n != 1 {
+				t.Errorf("made %d collection requests, want 1", n)
+			}
+		})
+	}
+}
+
+func TestCollectCardsBatches(t *testing.T) {
+	_, requests := useFakeScryfall(t)
+
+	// Only c21 263 exists, and it falls in the second batch
+	var entries []DeckEntry
+	for i := 0; i < collectionBatchSize+5; i++ {
+		entries = append(entries, DeckEntry{Line: i + 1, Quantity: 1, Name: "Sol Ring", Set: "c21", CollectorNumber: fmt.Sprint(1000 + i)})
+	}
+	found := collectionBatchSize + 2
+	entries[found].CollectorNumber = "263"
+
+	results := make([]lookupResult, len(entries))
+	pending := collectCards("Test", entries, results, getCardSource(), PrintingPreference{})
+	if len(pending) != 0 {
+		t.Errorf("pending = %v, want none", pending)
+	}
+	if n := requests.count("POST /cards/collection"); n != 2 {
+		t.Errorf("made %d collection requests, want 2", n)
+	}
+	for i, res := range results {
+		switch {
+		case i == found && (res.err != nil || res.card.CollectorNumber != "263"):
+			t.Errorf("line %d = %s/%s (%v), want c21/263", i+1, res.card.Set, res.card.CollectorNumber, res.err)
+		case i != found && res.err == nil:
+			t.Errorf("line %d resolved to %s/%s, want not found", i+1, res.card.Set, res.card.CollectorNumber)
+		}
+	}
+}
+
+func TestLookupCardsUnknownMTGOID(t *testing.T) {
+	useFakeScryfall(t)
+	deck := "<?xml version=\"1.0\"?>\n<Deck>\n" +
+		"  <Cards CatID=\"999999\" Quantity=\"1\" Sideboard=\"false\" Name=\"Sol Ring\" />\n" +
+		"  <Cards CatID=\"81024\" Quantity=\"1\" Sideboard=\"false\" Name=\"Lightning Bolt\" />\n" +
+		"  <Cards CatID=\"999998\" Quantity=\"1\" Sideboard=\"false\" Name=\"Black Lotus\" />\n" +
+		"</Deck>\n"
+	entries, errs := mtgoParser{}.Parse(deck, ParseOptions{})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	_, resolved, diagnostics := lookupCards("Test", entries, PrintingPreference{})
+	var got []string
+	for _, card := range resolved {
+		got = append(got, card.Set+"/"+card.CollectorNumber)
+	}
+	for _, diag := range diagnostics {
+		got = append(got, string(diag.Category))
+	}
+	if want := []string{"cmr/472", "2xm/141", string(DiagnosticNotFound)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Cards looked up one by one fall back to the name too
+	card, err := FetchCard(DeckEntry{Quantity: 1, MTGOID: 999999, Name: "Sol Ring", NameFallback: true}, getCardSource(), PrintingPreference{})
+	if err != nil || card.Set != "cmr" || card.CollectorNumber != "472" {
+		t.Errorf("FetchCard = %s/%s, %v, want cmr/472", card.Set, card.CollectorNumber, err)
+	}
+}
+
+func TestLookupCardsNameFallback(t *testing.T) {
+	tests := []struct {
+		name     string
+		parser   DecklistParser
+		decklist string
+		want     []string // "set/number" for cards, category for diagnostics
+	}{
+		{
+			name:     "Arena set codes Scryfall doesn't use",
+			parser:   arenaParser{},
+			decklist: "1 Sol Ring (Y22) 263\n1 Lightning Bolt (M11) 149\n1 Black Lotus (Y22) 1",
+			want:     []string{"cmr/472", "m11/149", string(DiagnosticUnknownSet)},
+		},
+		{
+			name:     "other formats keep the printing",
+			parser:   archidektParser{},
+			decklist: "1 Sol Ring (y22) 263\n1 Lightning Bolt (m11) 149",
+			want:     []string{"m11/149", string(DiagnosticUnknownSet)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeScryfall(t)
+			entries, errs := tt.parser.Parse(tt.decklist, ParseOptions{})
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+
+			_, resolved, diagnostics := lookupCards("Test", entries, PrintingPreference{})
+			var got []string
+			for _, card := range resolved {
+				got = append(got, card.Set+"/"+card.CollectorNumber)
+			}
+			for _, diag := range diagnostics {
+				got = append(got, string(diag.Category))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package job
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// CSV fields a column can be mapped to
+const (
+	csvQuantity        = "quantity"
+	csvName            = "name"
+	csvSet             = "set"
+	csvCollectorNumber = "collector_number"
+	csvFoil            = "foil"
+	csvLanguage        = "language"
+	csvScryfallID      = "scryfall_id"
+)
+
+// CSVColumns maps CSV fields (quantity, name, set, collector_number, foil,
+// language, scryfall_id) to the header of the column holding them
+type CSVColumns map[string]string
+
+// csvHeaders are the column headers recognised for each field when no mapping is given,
+// covering Deckbox, ManaBox and similar collection exports
+var csvHeaders = map[string][]string{
+	csvQuantity:        {"count", "quantity", "qty"},
+	csvName:            {"name", "card name", "card"},
+	csvSet:             {"set code", "edition code", "set"},
+	csvCollectorNumber: {"card number", "collector number", "collector_number", "number"},
+	csvFoil:            {"foil", "finish"},
+	csvLanguage:        {"language", "lang"},
+	csvScryfallID:      {"scryfall id", "scryfall_id"},
+}
+
+// ParseCSVColumns parses a column mapping in the form "quantity:Qty,name:Card Name"
+func ParseCSVColumns(mapping string) (CSVColumns, error) {
+	columns := CSVColumns{}
+	for _, pair := range strings.Split(mapping, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		field, header, ok := strings.Cut(pair, ":")
+		field = strings.ToLower(strings.TrimSpace(field))
+		if !ok || strings.TrimSpace(header) == "" {
+			return nil, fmt.Errorf("invalid CSV column mapping %q", pair)
+		}
+		if _, known := csvHeaders[field]; !known {
+			return nil, fmt.Errorf("unknown CSV field %q", field)
+		}
+		columns[field] = strings.TrimSpace(header)
+	}
+	return columns, nil
+}
+
+// Validate checks that every mapped field is known
+func (c CSVColumns) Validate() error {
+	for field := range c {
+		if _, known := csvHeaders[field]; !known {
+			return fmt.Errorf("unknown CSV field %q", field)
+		}
+	}
+	return nil
+}
+
+// csvLanguages maps language names used by collection exports to Scryfall language codes
+var csvLanguages = map[string]string{
+	"english":             "en",
+	"spanish":             "es",
+	"french":              "fr",
+	"german":              "de",
+	"italian":             "it",
+	"portuguese":          "pt",
+	"japanese":            "ja",
+	"korean":              "ko",
+	"russian":             "ru",
+	"chinese simplified":  "zhs",
+	"simplified chinese":  "zhs",
+	"chinese traditional": "zht",
+	"traditional chinese": "zht",
+}
+
+// csvParser parses CSV collection exports
+type csvParser struct{}
+
+func (csvParser) Format() DecklistFormat { return FormatCSV }
+
+// Detect reports whether the first line is a CSV header naming a card column
+func (csvParser) Detect(decklist string) bool {
+	header := strings.TrimSpace(strings.TrimPrefix(splitLines(decklist)[0], "\ufeff"))
+	delimiter := csvDelimiter(header)
+	if !strings.ContainsRune(header, delimiter) {
+		return false
+	}
+
+	r := csv.NewReader(strings.NewReader(header))
+	r.Comma = delimiter
+	r.LazyQuotes = true
+	r.TrimLeadingSpace = true
+	fields, err := r.Read()
+	if err != nil {
+		return false
+	}
+	_, err = csvColumnIndex(fields, nil)
+	return err == nil
+}
+
+func (csvParser) Parse(decklist string, opts ParseOptions) ([]DeckEntry, []error) {
+	return parseCSVDeck(decklist, opts.CSVColumns)
+}
+
+// parseCSVDeck parses a CSV collection export. The first row holds the column
+// headers; columns are found by the mapping in columns, falling back to the usual
+// Deckbox and ManaBox headers for unmapped fields.
+func parseCSVDeck(decklist string, columns CSVColumns) ([]DeckEntry, []error) {
+	decklist = strings.TrimPrefix(decklist, "\ufeff")
+	lines := splitLines(decklist)
+
+	r := csv.NewReader(strings.NewReader(decklist))
+	r.Comma = csvDelimiter(lines[0])
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, []error{&LineError{Line: 1, Text: strings.TrimSpace(lines[0]), Err: fmt.Errorf("invalid CSV header: %w", err)}}
+	}
+	index, err := csvColumnIndex(header, columns)
+	if err != nil {
+		return nil, []error{&LineError{Line: 1, Text: strings.TrimSpace(lines[0]), Err: err}}
+	}
+
+	var entries []DeckEntry
+	var errs []error
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line, _ := r.FieldPos(0)
+		if err != nil {
+			errs = append(errs, lineError(lines, line, err))
+			continue
+		}
+
+		field := func(name string) string {
+			if i, ok := index[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		entry := DeckEntry{
+			Quantity:        1,
+			Name:            field(csvName),
+			Set:             strings.ToLower(field(csvSet)),
+			CollectorNumber: field(csvCollectorNumber),
+			Finish:          csvFinish(field(csvFoil)),
+			Language:        languageCode(field(csvLanguage)),
+			ScryfallID:      field(csvScryfallID),
+		}
+		if entry.Name == "" && entry.ScryfallID == "" {
+			if strings.Join(record, "") != "" {
+				errs = append(errs, lineError(lines, line, fmt.Errorf("row has no card name")))
+			}
+			continue
+		}
+		if raw := field(csvQuantity); raw != "" {
+			if entry.Quantity, err = strconv.Atoi(raw); err != nil || entry.Quantity < 1 {
+				errs = append(errs, lineError(lines, line, fmt.Errorf("invalid quantity %q for %q", raw, entry.Name)))
+				continue
+			}
+		}
+		if entry.Set == "" {
+			entry.CollectorNumber = "" // a number means nothing without its set
+		}
+
+		entries = append(entries, entry.at(lines, line))
+	}
+	return entries, errs
+}
+
+// csvDelimiter guesses the delimiter of a CSV file from its header row
+func csvDelimiter(header string) rune {
+	delimiter, most := ',', strings.Count(header, ",")
+	for _, d := range []rune{';', '\t'} {
+		if n := strings.Count(header, string(d)); n > most {
+			delimiter, most = d, n
+		}
+	}
+	return delimiter
+}
+
+// csvColumnIndex returns the index of the column holding each field
+func csvColumnIndex(header []string, columns CSVColumns) (map[string]int, error) {
+	positions := make(map[string]int, len(header))
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if _, ok := positions[h]; !ok {
+			positions[h] = i
+		}
+	}
+
+	index := make(map[string]int)
+	for field, aliases := range csvHeaders {
+		if mapped, ok := columns[field]; ok {
+			i, found := positions[strings.ToLower(mapped)]
+			if !found {
+				return nil, fmt.Errorf("CSV has no %q column for %s", mapped, field)
+			}
+			index[field] = i
+			continue
+		}
+		for _, alias := range aliases {
+			if i, found := positions[alias]; found {
+				index[field] = i
+				break
+			}
+		}
+	}
+
+	_, hasName := index[csvName]
+	_, hasID := index[csvScryfallID]
+	if !hasName && !hasID {
+		return nil, fmt.Errorf("CSV has no card name column")
+	}
+	return index, nil
+}
+
+// csvFinish returns the card finish for a Foil column value
+func csvFinish(value string) string {
+	switch strings.ToLower(value) {
+	case "foil", "true", "yes", "y", "1":
+		return "foil"
+	case "etched":
+		return "etched"
+	}
+	return ""
+}
+
+// languageCode returns the Scryfall language code for a Language column value
+func languageCode(value string) string {
+	value = strings.ToLower(value)
+	if code, ok := csvLanguages[value]; ok {
+		return code
+	}
+	return value
+}
@@ -0,0 +1,194 @@
+package job
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// mtgoParser parses MTGO .dek files
+type mtgoParser struct{}
+
+func (mtgoParser) Format() DecklistFormat { return FormatMTGO }
+
+func (mtgoParser) Detect(decklist string) bool {
+	return strings.Contains(decklist, "<Deck") && strings.Contains(decklist, "<Cards")
+}
+
+func (mtgoParser) Parse(decklist string, _ ParseOptions) ([]DeckEntry, []error) {
+	return parseMTGODeck(decklist)
+}
+
+// mtgoCard is a <Cards> element of an MTGO .dek file
+type mtgoCard struct {
+	CatID     string `xml:"CatID,attr"`
+	Quantity  string `xml:"Quantity,attr"`
+	Sideboard string `xml:"Sideboard,attr"`
+	Name      string `xml:"Name,attr"`
+}
+
+// parseMTGODeck parses an MTGO .dek XML file. Cards are looked up by their MTGO
+// catalog ID, or by name when the file doesn't carry one.
+func parseMTGODeck(decklist string) ([]DeckEntry, []error) {
+	lines := strings.Split(decklist, "\n")
+	var entries []DeckEntry
+	var errs []error
+	err := decodeDeckXML(decklist, "Cards", func(d *xml.Decoder, start xml.StartElement, line int) error {
+		var card mtgoCard
+		if err := d.DecodeElement(&card, &start); err != nil {
+			return err
+		}
+
+		entry := DeckEntry{Name: strings.TrimSpace(card.Name), Section: SectionMainboard}
+		if strings.EqualFold(card.Sideboard, "true") {
+			entry.Section = SectionSideboard
+		}
+
+		var err error
+		if entry.Quantity, err = strconv.Atoi(card.Quantity); err != nil || entry.Quantity < 1 {
+			errs = append(errs, lineError(lines, line, fmt.Errorf("invalid quantity %q for %q", card.Quantity, card.Name)).in(entry.Section))
+			return nil
+		}
+		if card.CatID != "" {
+			if entry.MTGOID, err = strconv.Atoi(card.CatID); err != nil {
+				errs = append(errs, lineError(lines, line, fmt.Errorf("invalid CatID %q for %q", card.CatID, card.Name)).in(entry.Section))
+				return nil
+			}
+		}
+		// MTGO has catalog IDs Scryfall doesn't, and those cards can still be found by name
+		entry.NameFallback = entry.MTGOID != 0
+		if entry.MTGOID == 0 && entry.Name == "" {
+			errs = append(errs, lineError(lines, line, fmt.Errorf("card has neither a CatID nor a name")).in(entry.Section))
+			return nil
+		}
+
+		entries = append(entries, entry.at(lines, line))
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, &LineError{Err: fmt.Errorf("invalid MTGO deck file: %w", err)})
+	}
+	return entries, errs
+}
+
+// cockatriceParser parses Cockatrice .cod files
+type cockatriceParser struct{}
+
+func (cockatriceParser) Format() DecklistFormat { return FormatCockatrice }
+
+func (cockatriceParser) Detect(decklist string) bool {
+	return strings.Contains(decklist, "<cockatrice_deck")
+}
+
+func (cockatriceParser) Parse(decklist string, _ ParseOptions) ([]DeckEntry, []error) {
+	return parseCockatriceDeck(decklist)
+}
+
+// cockatriceCard is a <card> element of a Cockatrice .cod file. Newer versions
+// of Cockatrice also record the printing.
+type cockatriceCard struct {
+	Number          string `xml:"number,attr"`
+	Name            string `xml:"name,attr"`
+	SetShortName    string `xml:"setShortName,attr"`
+	CollectorNumber string `xml:"collectorNumber,attr"`
+}
+
+// cockatriceZones maps Cockatrice zone names to decklist sections
+var cockatriceZones = map[string]string{
+	"main":   SectionMainboard,
+	"side":   SectionSideboard,
+	"tokens": SectionTokens,
+}
+
+// parseCockatriceDeck parses a Cockatrice .cod XML file. Cards are looked up by
+// name, or by printing when the file records one.
+func parseCockatriceDeck(decklist string) ([]DeckEntry, []error) {
+	lines := strings.Split(decklist, "\n")
+	var entries []DeckEntry
+	var errs []error
+	section := ""
+	err := decodeDeckXML(decklist, "", func(d *xml.Decoder, start xml.StartElement, line int) error {
+		if start.Name.Local == "zone" {
+			section = ""
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "name" {
+					section = cockatriceZones[attr.Value]
+					if section == "" {
+						section = canonicalSection(attr.Value)
+					}
+				}
+			}
+			return nil
+		}
+		if start.Name.Local != "card" {
+			return nil
+		}
+
+		var card cockatriceCard
+		if err := d.DecodeElement(&card, &start); err != nil {
+			return err
+		}
+
+		quantity, err := strconv.Atoi(card.Number)
+		if err != nil || quantity < 1 {
+			errs = append(errs, lineError(lines, line, fmt.Errorf("invalid number %q for %q", card.Number, card.Name)).in(section))
+			return nil
+		}
+		if strings.TrimSpace(card.Name) == "" {
+			errs = append(errs, lineError(lines, line, fmt.Errorf("card has no name")).in(section))
+			return nil
+		}
+
+		entry := DeckEntry{
+			Quantity: quantity,
+			Name:     strings.TrimSpace(card.Name),
+			Section:  section,
+		}
+		if card.SetShortName != "" {
+			entry.Set = strings.ToLower(card.SetShortName)
+			entry.CollectorNumber = card.CollectorNumber
+		}
+		entries = append(entries, entry.at(lines, line))
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, &LineError{Err: fmt.Errorf("invalid Cockatrice deck file: %w", err)})
+	}
+	return entries, errs
+}
+
+// decodeDeckXML calls fn with every start element named element (or every start
+// element when element is empty) and the decklist line it ends on
+func decodeDeckXML(decklist, element string, fn func(d *xml.Decoder, start xml.StartElement, line int) error) error {
+	d := xml.NewDecoder(strings.NewReader(strings.TrimPrefix(decklist, "\ufeff")))
+	for {
+		token, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || (element != "" && start.Name.Local != element) {
+			continue
+		}
+		line, _ := d.InputPos()
+		if err := fn(d, start, line); err != nil {
+			return err
+		}
+	}
+}
+
+// at returns the entry with its position set to the given decklist line
+func (e DeckEntry) at(lines []string, line int) DeckEntry {
+	e.Line = line
+	if line >= 1 && line <= len(lines) {
+		e.Raw = strings.TrimSpace(lines[line-1])
+	}
+	return e
+}
@@ -0,0 +1,418 @@
+package job
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DecklistFormat names a decklist export format
+type DecklistFormat string
+
+const (
+	FormatAuto       DecklistFormat = "auto"       // detect the format from the decklist
+	FormatArchidekt  DecklistFormat = "archidekt"  // 1 Name (set) number
+	FormatMoxfield   DecklistFormat = "moxfield"   // 1 Name (SET) number *F*, number optional
+	FormatArena      DecklistFormat = "arena"      // 1 Name (SET) number, set and number optional
+	FormatMTGO       DecklistFormat = "mtgo"       // MTGO .dek XML file
+	FormatCockatrice DecklistFormat = "cockatrice" // Cockatrice .cod XML file
+	FormatCSV        DecklistFormat = "csv"        // Deckbox, ManaBox and other CSV exports
+)
+
+// DecklistParser parses one decklist format into card entries
+type DecklistParser interface {
+	// Format returns the name that selects the parser in the submit API
+	Format() DecklistFormat
+	// Detect reports whether decklist looks like it is in this format
+	Detect(decklist string) bool
+	// Parse splits decklist into card entries, returning a *LineError for every line that can't be parsed
+	Parse(decklist string, opts ParseOptions) ([]DeckEntry, []error)
+}
+
+// ParseOptions holds the per-job settings some parsers need
+type ParseOptions struct {
+	CSVColumns CSVColumns // column mapping for the CSV format
+}
+
+// Registered decklist parsers in detection order
+var (
+	decklistParsers   []DecklistParser
+	decklistParsersMu sync.RWMutex
+)
+
+func init() {
+	// Structured formats are unambiguous, so they are tried first; Archidekt,
+	// the strictest text format, is the fallback
+	RegisterDecklistParser(mtgoParser{})
+	RegisterDecklistParser(cockatriceParser{})
+	RegisterDecklistParser(csvParser{})
+	RegisterDecklistParser(arenaParser{})
+	RegisterDecklistParser(moxfieldParser{})
+	RegisterDecklistParser(archidektParser{})
+}
+
+// RegisterDecklistParser adds a decklist parser, or replaces the one registered for
+// the same format. New formats are detected after the ones already registered.
+func RegisterDecklistParser(parser DecklistParser) {
+	decklistParsersMu.Lock()
+	defer decklistParsersMu.Unlock()
+	for i, p := range decklistParsers {
+		if p.Format() == parser.Format() {
+			decklistParsers[i] = parser
+			return
+		}
+	}
+	decklistParsers = append(decklistParsers, parser)
+}
+
+// DecklistParserFor returns the parser registered for format
+func DecklistParserFor(format DecklistFormat) (DecklistParser, error) {
+	decklistParsersMu.RLock()
+	defer decklistParsersMu.RUnlock()
+	for _, p := range decklistParsers {
+		if p.Format() == format {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown decklist format %q", format)
+}
+
+// DecklistFormats returns the registered formats in detection order
+func DecklistFormats() []DecklistFormat {
+	decklistParsersMu.RLock()
+	defer decklistParsersMu.RUnlock()
+	formats := make([]DecklistFormat, len(decklistParsers))
+	for i, p := range decklistParsers {
+		formats[i] = p.Format()
+	}
+	return formats
+}
+
+// ParseDecklistFormat validates a decklist format from the submit API. An empty format selects FormatAuto.
+func ParseDecklistFormat(format string) (DecklistFormat, error) {
+	f := DecklistFormat(strings.ToLower(strings.TrimSpace(format)))
+	if f == "" || f == FormatAuto {
+		return FormatAuto, nil
+	}
+	if _, err := DecklistParserFor(f); err != nil {
+		return "", err
+	}
+	return f, nil
+}
+
+// DetectDecklistFormat returns the format of the first registered parser that
+// recognises decklist, or FormatArchidekt when none does
+func DetectDecklistFormat(decklist string) DecklistFormat {
+	decklistParsersMu.RLock()
+	defer decklistParsersMu.RUnlock()
+	for _, p := range decklistParsers {
+		if p.Detect(decklist) {
+			return p.Format()
+		}
+	}
+	return FormatArchidekt
+}
+
+// DeckEntry is one card line of a decklist, before it is looked up on Scryfall
+type DeckEntry struct {
+	Line            int    // 1-based line number in the decklist
+	Raw             string // the line as written
+	Quantity        int
+	Name            string
+	Set             string // empty when the line doesn't name a printing
+	CollectorNumber string // empty when only the set is known
+	MTGOID          int    // MTGO catalog ID, used instead of the name and printing when set
+	ScryfallID      string // Scryfall card ID, used instead of the name and printing when set
+	Language        string // Scryfall language code of the printing, empty for English
+	Finish          string // "foil" or "etched" when marked, otherwise empty
+	Section         string // decklist section the line was listed under, empty if none
+	// NameFallback looks the card up by name when its printing isn't found, for
+	// formats whose catalog IDs and set codes Scryfall doesn't always know
+	NameFallback bool
+}
+
+// ParseDecklist parses a decklist in the given format, detecting the format first
+// for FormatAuto. It returns the format used along with the entries and line errors.
+func ParseDecklist(decklist string, format DecklistFormat, opts ParseOptions) (DecklistFormat, []DeckEntry, []error) {
+	if format == FormatAuto {
+		format = DetectDecklistFormat(decklist)
+	}
+	parser, err := DecklistParserFor(format)
+	if err != nil {
+		return format, nil, []error{&LineError{Err: err}}
+	}
+	entries, errs := parser.Parse(decklist, opts)
+	return format, entries, errs
+}
+
+// parseTextDecklist splits a line-based decklist into card entries. Blank lines are
+// skipped and section headers apply to the lines below them. Lines that can't be
+// parsed are returned as LineErrors, one per line.
+func parseTextDecklist(decklist string, parseLine func(line string) (DeckEntry, error)) ([]DeckEntry, []error) {
+	var entries []DeckEntry
+	var errs []error
+	section := ""
+	for i, line := range splitLines(decklist) {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if name, ok := sectionHeader(trimmed); ok {
+			section = name
+			continue
+		}
+		if section == "about" {
+			continue // deck metadata such as "Name My Deck"
+		}
+
+		entry, err := parseLine(trimmed)
+		if err != nil {
+			errs = append(errs, &LineError{Line: i + 1, Text: trimmed, Section: section, Err: err})
+			continue
+		}
+		entry.Line = i + 1
+		entry.Raw = line
+		if entry.Section == "" {
+			entry.Section = section
+		}
+		entries = append(entries, entry)
+	}
+	return entries, errs
+}
+
+// splitLines splits a decklist into lines, whatever line endings it uses
+func splitLines(decklist string) []string {
+	decklist = strings.ReplaceAll(decklist, "\r\n", "\n")
+	decklist = strings.ReplaceAll(decklist, "\r", "\n")
+	return strings.Split(decklist, "\n")
+}
+
+// cardLines returns the trimmed lines of a text decklist that aren't blank or section headers
+func cardLines(decklist string) []string {
+	var lines []string
+	for _, line := range splitLines(decklist) {
+		line = strings.TrimSpace(line)
+		if _, header := sectionHeader(line); line != "" && !header {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// nameOnlyLineRe matches "4 Lightning Bolt" or "4x Lightning Bolt"
+var nameOnlyLineRe = regexp.MustCompile(`^(\d+)x?\s+([^()]+)$`)
+
+// parseNameOnlyLine parses a line giving only a quantity and card name. The
+// printing is picked when the card is looked up.
+func parseNameOnlyLine(line string) (DeckEntry, error) {
+	matches := nameOnlyLineRe.FindStringSubmatch(strings.TrimSpace(line))
+	if matches == nil {
+		return DeckEntry{}, fmt.Errorf("could not parse line: %q", line)
+	}
+
+	quantity, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return DeckEntry{}, fmt.Errorf("invalid quantity: %w", err)
+	}
+	return DeckEntry{Quantity: quantity, Name: strings.TrimSpace(matches[2])}, nil
+}
+
+// archidektParser parses Archidekt text exports
+type archidektParser struct{}
+
+func (archidektParser) Format() DecklistFormat { return FormatArchidekt }
+
+// Detect reports whether every card line has a set and collector number
+func (archidektParser) Detect(decklist string) bool {
+	lines := cardLines(decklist)
+	for _, line := range lines {
+		line, _, _ = splitArchidektTags(line)
+		if !archidektLineRe.MatchString(line) && !archidektFallbackRe.MatchString(line) {
+			return false
+		}
+	}
+	return len(lines) > 0
+}
+
+func (archidektParser) Parse(decklist string, _ ParseOptions) ([]DeckEntry, []error) {
+	return parseTextDecklist(decklist, parseArchidektLine)
+}
+
+var (
+	archidektLineRe     = regexp.MustCompile(`^(\d+)x?\s+(.+?)\s+\(([^)]+)\)\s+([^\s\r\n]+)$`)
+	archidektFallbackRe = regexp.MustCompile(`^(\d+)x?\s+(.+?)\s+\(([^)]+)\)\s+(.+)$`)
+)
+
+// parseArchidektLine parses a line in the form "1 Name (set) number", optionally
+// followed by a finish marker and categories as in "*F* [Commander{top}]"
+func parseArchidektLine(line string) (DeckEntry, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return DeckEntry{}, fmt.Errorf("empty line")
+	}
+	line, finish, section := splitArchidektTags(line)
+
+	matches := archidektLineRe.FindStringSubmatch(line)
+	if matches == nil {
+		matches = archidektFallbackRe.FindStringSubmatch(line)
+		if matches == nil {
+			entry, err := parseNameOnlyLine(line)
+			entry.Finish, entry.Section = finish, section
+			return entry, err
+		}
+		matches[4] = strings.TrimSpace(matches[4])
+	}
+
+	quantity, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return DeckEntry{}, fmt.Errorf("invalid quantity: %w", err)
+	}
+
+	return DeckEntry{
+		Quantity:        quantity,
+		Name:            strings.TrimSpace(matches[2]),
+		Set:             matches[3],
+		CollectorNumber: matches[4],
+		Finish:          finish,
+		Section:         section,
+	}, nil
+}
+
+// moxfieldParser parses Moxfield text exports
+type moxfieldParser struct{}
+
+func (moxfieldParser) Format() DecklistFormat { return FormatMoxfield }
+
+// Detect reports whether the card lines use Moxfield's finish markers, upper case
+// set codes or leave out collector numbers, none of which Archidekt does
+func (moxfieldParser) Detect(decklist string) bool {
+	lines := cardLines(decklist)
+	moxfield := false
+	for _, line := range lines {
+		matches := moxfieldLineRe.FindStringSubmatch(line)
+		if matches == nil {
+			return false
+		}
+		if matches[4] == "" || matches[5] != "" || matches[3] != strings.ToLower(matches[3]) {
+			moxfield = true
+		}
+	}
+	return moxfield
+}
+
+func (moxfieldParser) Parse(decklist string, _ ParseOptions) ([]DeckEntry, []error) {
+	return parseTextDecklist(decklist, parseMoxfieldLine)
+}
+
+// Moxfield lines are "1 Name (SET) number" followed by optional finish markers
+// such as *F*; the collector number is left out for some cards
+var moxfieldLineRe = regexp.MustCompile(`^(\d+)x?\s+(.+?)\s+\(([^)\s]+)\)(?:\s+([^\s*]+))?((?:\s+\*[A-Za-z]+\*)*)$`)
+
+// moxfieldFinishes maps Moxfield finish markers to card finishes
+var moxfieldFinishes = map[string]string{
+	"*F*": "foil",
+	"*E*": "etched",
+}
+
+// parseMoxfieldLine parses a line of a Moxfield export
+func parseMoxfieldLine(line string) (DeckEntry, error) {
+	matches := moxfieldLineRe.FindStringSubmatch(strings.TrimSpace(line))
+	if matches == nil {
+		return parseNameOnlyLine(line)
+	}
+
+	quantity, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return DeckEntry{}, fmt.Errorf("invalid quantity: %w", err)
+	}
+
+	entry := DeckEntry{
+		Quantity:        quantity,
+		Name:            strings.TrimSpace(matches[2]),
+		Set:             strings.ToLower(matches[3]),
+		CollectorNumber: matches[4],
+	}
+	for _, marker := range strings.Fields(matches[5]) {
+		if finish, ok := moxfieldFinishes[strings.ToUpper(marker)]; ok {
+			entry.Finish = finish
+		}
+	}
+	return entry, nil
+}
+
+// arenaParser parses MTG Arena exports
+type arenaParser struct{}
+
+func (arenaParser) Format() DecklistFormat { return FormatArena }
+
+// Detect reports whether the decklist has Arena's "About" or "Deck" headers, or
+// card lines without a set, which no other text format allows
+func (arenaParser) Detect(decklist string) bool {
+	for _, line := range splitLines(decklist) {
+		if line = strings.TrimSpace(line); line == "About" || line == "Deck" {
+			return true
+		}
+	}
+
+	lines := cardLines(decklist)
+	nameOnly := false
+	for _, line := range lines {
+		matches := arenaLineRe.FindStringSubmatch(line)
+		if matches == nil {
+			return false
+		}
+		if matches[3] == "" {
+			if strings.ContainsAny(matches[2], "()*") {
+				return false // a set or marker Arena doesn't write
+			}
+			nameOnly = true
+		}
+	}
+	return nameOnly
+}
+
+func (arenaParser) Parse(decklist string, _ ParseOptions) ([]DeckEntry, []error) {
+	return parseTextDecklist(decklist, parseArenaLine)
+}
+
+// Arena lines are "4 Name (SET) number", where the set and number may be missing
+var arenaLineRe = regexp.MustCompile(`^(\d+)\s+(.+?)(?:\s+\(([A-Za-z0-9_]+)\)(?:\s+(\S+))?)?$`)
+
+// arenaSetCodes maps set codes used by MTG Arena to their Scryfall codes where they
+// differ. Codes shared by several Scryfall sets, such as Alchemy's yearly Y22 and Y23,
+// can't be mapped, so Arena cards that aren't found are looked up by name instead.
+var arenaSetCodes = map[string]string{
+	"DAR":  "dom", // Dominaria
+	"CONF": "con", // Conflux
+}
+
+// parseArenaLine parses a line of an MTG Arena export
+func parseArenaLine(line string) (DeckEntry, error) {
+	matches := arenaLineRe.FindStringSubmatch(strings.TrimSpace(line))
+	if matches == nil {
+		return DeckEntry{}, fmt.Errorf("could not parse line: %q", line)
+	}
+
+	quantity, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return DeckEntry{}, fmt.Errorf("invalid quantity: %w", err)
+	}
+
+	set := strings.ToUpper(matches[3])
+	if code, ok := arenaSetCodes[set]; ok {
+		set = code
+	}
+
+	// Arena writes split cards as "Expansion /// Explosion"
+	name := strings.ReplaceAll(strings.TrimSpace(matches[2]), " /// ", " // ")
+
+	return DeckEntry{
+		Quantity:        quantity,
+		Name:            name,
+		Set:             strings.ToLower(set),
+		CollectorNumber: matches[4],
+		NameFallback:    set != "",
+	}, nil
+}
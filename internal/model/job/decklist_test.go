@@ -0,0 +1,170 @@
+package job
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecklistParsers(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   DecklistFormat
+		decklist string
+		want     []DeckEntry
+		errLines []int // lines reported as LineErrors
+	}{
+		{
+			name:     "archidekt",
+			format:   FormatArchidekt,
+			decklist: "1 Xyris, the Writhing Storm (dmc) 175\n1x Sol Ring (c21) 263 *F* [Ramp]\n\nSideboard\n2 Forest (iko) 258\n1 Lightning Bolt\n1 Opt (xln) 65 [Maybeboard{noDeck}]",
+			want: []DeckEntry{
+				{Line: 1, Quantity: 1, Name: "Xyris, the Writhing Storm", Set: "dmc", CollectorNumber: "175"},
+				{Line: 2, Quantity: 1, Name: "Sol Ring", Set: "c21", CollectorNumber: "263", Finish: "foil", Section: "ramp"},
+				{Line: 5, Quantity: 2, Name: "Forest", Set: "iko", CollectorNumber: "258", Section: SectionSideboard},
+				{Line: 6, Quantity: 1, Name: "Lightning Bolt", Section: SectionSideboard},
+				{Line: 7, Quantity: 1, Name: "Opt", Set: "xln", CollectorNumber: "65", Section: SectionMaybeboard},
+			},
+		},
+		{
+			name:     "archidekt bad line",
+			format:   FormatArchidekt,
+			decklist: "1 Sol Ring (c21) 263\nnot a card",
+			want: []DeckEntry{
+				{Line: 1, Quantity: 1, Name: "Sol Ring", Set: "c21", CollectorNumber: "263"},
+			},
+			errLines: []int{2},
+		},
+		{
+			name:     "moxfield",
+			format:   FormatMoxfield,
+			decklist: "1 Sol Ring (C21) 263 *F*\n1 Arcane Signet (CMR)\n\nSIDEBOARD:\n1 Brazen Borrower // Petty Theft (ELD) 39 *E*\n1 Forest (UNF) 235★",
+			want: []DeckEntry{
+				{Line: 1, Quantity: 1, Name: "Sol Ring", Set: "c21", CollectorNumber: "263", Finish: "foil"},
+				{Line: 2, Quantity: 1, Name: "Arcane Signet", Set: "cmr"},
+				{Line: 5, Quantity: 1, Name: "Brazen Borrower // Petty Theft", Set: "eld", CollectorNumber: "39", Finish: "etched", Section: SectionSideboard},
+				{Line: 6, Quantity: 1, Name: "Forest", Set: "unf", CollectorNumber: "235★", Section: SectionSideboard},
+			},
+		},
+		{
+			name:     "arena",
+			format:   FormatArena,
+			decklist: "About\nName Mono Green\n\nCommander\n1 Ghalta, Primal Hunger (RIX) 130\n\nDeck\n4 Llanowar Elves (DAR) 168\n2 Gift of Paradise (CONF)\n1 Expansion /// Explosion (GRN) 224\n20 Forest\n\nSideboard\n2 Duress (M19) 94",
+			want: []DeckEntry{
+				{Line: 5, Quantity: 1, Name: "Ghalta, Primal Hunger", Set: "rix", CollectorNumber: "130", Section: SectionCommander, NameFallback: true},
+				{Line: 8, Quantity: 4, Name: "Llanowar Elves", Set: "dom", CollectorNumber: "168", Section: SectionMainboard, NameFallback: true},
+				{Line: 9, Quantity: 2, Name: "Gift of Paradise", Set: "con", Section: SectionMainboard, NameFallback: true},
+				{Line: 10, Quantity: 1, Name: "Expansion // Explosion", Set: "grn", CollectorNumber: "224", Section: SectionMainboard, NameFallback: true},
+				{Line: 11, Quantity: 20, Name: "Forest", Section: SectionMainboard},
+				{Line: 14, Quantity: 2, Name: "Duress", Set: "m19", CollectorNumber: "94", Section: SectionSideboard, NameFallback: true},
+			},
+		},
+		{
+			name:   "mtgo",
+			format: FormatMTGO,
+			decklist: "<?xml version=\"1.0\" encoding=\"utf-8\"?>\n<Deck>\n" +
+				"  <Cards CatID=\"48234\" Quantity=\"4\" Sideboard=\"false\" Name=\"Lightning Bolt\" />\n" +
+				"  <Cards CatID=\"\" Quantity=\"2\" Sideboard=\"true\" Name=\"Duress\" />\n" +
+				"  <Cards CatID=\"x\" Quantity=\"2\" Sideboard=\"true\" Name=\"Bad\" />\n</Deck>\n",
+			want: []DeckEntry{
+				{Line: 3, Quantity: 4, Name: "Lightning Bolt", MTGOID: 48234, Section: SectionMainboard, NameFallback: true},
+				{Line: 4, Quantity: 2, Name: "Duress", Section: SectionSideboard},
+			},
+			errLines: []int{5},
+		},
+		{
+			name:   "cockatrice",
+			format: FormatCockatrice,
+			decklist: "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<cockatrice_deck version=\"1\">\n    <zone name=\"main\">\n" +
+				"        <card number=\"4\" name=\"Lightning Bolt\"/>\n" +
+				"        <card number=\"1\" name=\"Sol Ring\" setShortName=\"C21\" collectorNumber=\"263\"/>\n" +
+				"    </zone>\n    <zone name=\"side\">\n        <card number=\"2\" name=\"Duress\"/>\n    </zone>\n</cockatrice_deck>\n",
+			want: []DeckEntry{
+				{Line: 4, Quantity: 4, Name: "Lightning Bolt", Section: SectionMainboard},
+				{Line: 5, Quantity: 1, Name: "Sol Ring", Set: "c21", CollectorNumber: "263", Section: SectionMainboard},
+				{Line: 8, Quantity: 2, Name: "Duress", Section: SectionSideboard},
+			},
+		},
+		{
+			name:     "deckbox csv",
+			format:   FormatCSV,
+			decklist: "Count,Tradelist Count,Name,Edition,Edition Code,Card Number,Condition,Language,Foil,Signed\n4,0,Lightning Bolt,Magic 2010,M10,146,Near Mint,English,,\n1,0,\"Ghalta, Primal Hunger\",Rivals of Ixalan,RIX,130,Near Mint,Japanese,foil,\n",
+			want: []DeckEntry{
+				{Line: 2, Quantity: 4, Name: "Lightning Bolt", Set: "m10", CollectorNumber: "146", Language: "en"},
+				{Line: 3, Quantity: 1, Name: "Ghalta, Primal Hunger", Set: "rix", CollectorNumber: "130", Language: "ja", Finish: "foil"},
+			},
+		},
+		{
+			name:     "manabox csv",
+			format:   FormatCSV,
+			decklist: "Name;Set code;Set name;Collector number;Foil;Rarity;Quantity;ManaBox ID;Scryfall ID\nSol Ring;c21;Commander 2021;263;etched;uncommon;1;1;abc-123\nForest;;;;normal;common;x;;\n",
+			want: []DeckEntry{
+				{Line: 2, Quantity: 1, Name: "Sol Ring", Set: "c21", CollectorNumber: "263", ScryfallID: "abc-123", Finish: "etched"},
+			},
+			errLines: []int{3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := DecklistParserFor(tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entries, errs := parser.Parse(tt.decklist, ParseOptions{})
+			for i := range entries {
+				entries[i].Raw = ""
+			}
+			if !reflect.DeepEqual(entries, tt.want) {
+				t.Errorf("entries:\n got %+v\nwant %+v", entries, tt.want)
+			}
+
+			var errLines []int
+			for _, err := range errs {
+				var lineErr *LineError
+				if !errors.As(err, &lineErr) {
+					t.Fatalf("error %v is not a *LineError", err)
+				}
+				errLines = append(errLines, lineErr.Line)
+			}
+			if !reflect.DeepEqual(errLines, tt.errLines) {
+				t.Errorf("error lines = %v, want %v (%v)", errLines, tt.errLines, errs)
+			}
+		})
+	}
+}
+
+func TestDetectDecklistFormat(t *testing.T) {
+	tests := []struct {
+		decklist string
+		want     DecklistFormat
+	}{
+		{"1 Xyris, the Writhing Storm (dmc) 175\nSideboard\n2 Forest (iko) 258", FormatArchidekt},
+		{"1 Sol Ring (C21) 263 *F*\n\nSIDEBOARD:\n1 Arcane Signet (CMR)", FormatMoxfield},
+		{"1 Sol Ring (C21) 263\n1 Arcane Signet (CMR) 12", FormatMoxfield},
+		{"About\nName X\n\nDeck\n4 Llanowar Elves (DAR) 168", FormatArena},
+		{"4 Lightning Bolt\n2 Opt (XLN) 65", FormatArena},
+		{"<?xml version=\"1.0\"?>\n<Deck><Cards CatID=\"1\" Quantity=\"1\" Name=\"X\"/></Deck>", FormatMTGO},
+		{"<cockatrice_deck version=\"1\"></cockatrice_deck>", FormatCockatrice},
+		{"Count,Name,Edition Code\n1,Opt,xln", FormatCSV},
+		{"Name;Set code;Quantity\nOpt;xln;1", FormatCSV},
+		{"garbage", FormatArchidekt},
+	}
+	for _, tt := range tests {
+		if got := DetectDecklistFormat(tt.decklist); got != tt.want {
+			t.Errorf("DetectDecklistFormat(%q) = %s, want %s", tt.decklist, got, tt.want)
+		}
+	}
+}
+
+func TestParseDecklistFormat(t *testing.T) {
+	if f, err := ParseDecklistFormat(" "); f != FormatAuto || err != nil {
+		t.Errorf("ParseDecklistFormat(\" \") = %q, %v, want auto", f, err)
+	}
+	if f, err := ParseDecklistFormat("Moxfield"); f != FormatMoxfield || err != nil {
+		t.Errorf("ParseDecklistFormat(\"Moxfield\") = %q, %v, want moxfield", f, err)
+	}
+	if _, err := ParseDecklistFormat("nope"); err == nil {
+		t.Error("ParseDecklistFormat(\"nope\") accepted an unknown format")
+	}
+}
@@ -0,0 +1,140 @@
+package job
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// DiagnosticCategory classifies why a decklist line couldn't be resolved
+type DiagnosticCategory string
+
+const (
+	DiagnosticSyntax      DiagnosticCategory = "syntax"       // the line couldn't be parsed
+	DiagnosticUnknownSet  DiagnosticCategory = "unknown_set"  // Scryfall has no set with the line's set code
+	DiagnosticNotFound    DiagnosticCategory = "not_found"    // Scryfall has no card matching the line
+	DiagnosticRateLimited DiagnosticCategory = "rate_limited" // Scryfall kept rejecting requests
+	DiagnosticNetwork     DiagnosticCategory = "network"      // Scryfall couldn't be reached
+	DiagnosticAPI         DiagnosticCategory = "api_error"    // Scryfall answered with an error or an unreadable response
+)
+
+// Diagnostic describes a decklist line that couldn't be resolved to a card
+type Diagnostic struct {
+	Line       int                `json:"line"` // 1-based line number, 0 for the whole decklist
+	Text       string             `json:"text"`
+	Category   DiagnosticCategory `json:"category"`
+	Message    string             `json:"message"`
+	Suggestion string             `json:"suggestion,omitempty"`
+}
+
+// LineError is a decklist line that couldn't be parsed
+type LineError struct {
+	Line    int    // 1-based line number, 0 when the error applies to the whole decklist
+	Text    string // the line as written
+	Section string // the decklist section the line is listed under
+	Err     error
+}
+
+func (e *LineError) Error() string {
+	if e.Line == 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("line %d: failed to parse %q: %v", e.Line, e.Text, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// lineError returns a LineError for the given 1-based line of lines
+func lineError(lines []string, line int, err error) *LineError {
+	lineErr := &LineError{Line: line, Err: err}
+	if line >= 1 && line <= len(lines) {
+		lineErr.Text = strings.TrimSpace(lines[line-1])
+	}
+	return lineErr
+}
+
+// in returns e with the decklist section its line is listed under
+func (e *LineError) in(section string) *LineError {
+	e.Section = section
+	return e
+}
+
+// sortDiagnostics orders diagnostics by decklist line
+func sortDiagnostics(diagnostics []Diagnostic) {
+	slices.SortStableFunc(diagnostics, func(a, b Diagnostic) int { return a.Line - b.Line })
+}
+
+// syntaxSuggestion is offered for lines that couldn't be parsed
+const syntaxSuggestion = `Write card lines as "1 Card Name (set) 123" or "1 Card Name"`
+
+// parseDiagnostic describes an error returned by a DecklistParser
+func parseDiagnostic(err error) Diagnostic {
+	diag := Diagnostic{Category: DiagnosticSyntax, Message: err.Error()}
+	var lineErr *LineError
+	if errors.As(err, &lineErr) {
+		diag.Line = lineErr.Line
+		diag.Text = lineErr.Text
+		diag.Message = lineErr.Err.Error()
+	}
+	if diag.Line > 0 {
+		diag.Suggestion = syntaxSuggestion
+	}
+	return diag
+}
+
+// lookupDiagnostic describes why entry couldn't be looked up on Scryfall. Cards that
+// aren't found are checked further to tell an unknown set from a misspelt name.
+func lookupDiagnostic(entry DeckEntry, err error, source CardSource) Diagnostic {
+	diag := Diagnostic{
+		Line:    entry.Line,
+		Text:    strings.TrimSpace(entry.Raw),
+		Message: err.Error(),
+	}
+
+	var apiErr *APIError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+		diag.Category = DiagnosticNotFound
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests:
+		diag.Category = DiagnosticRateLimited
+		diag.Suggestion = "Scryfall is rate limiting requests; wait a minute and submit again"
+		return diag
+	case errors.As(err, &netErr):
+		diag.Category = DiagnosticNetwork
+		diag.Suggestion = "Scryfall could not be reached; check the connection and submit again"
+		return diag
+	case errors.As(err, &apiErr) && apiErr.StatusCode >= 500:
+		diag.Category = DiagnosticAPI
+		diag.Suggestion = "Scryfall had a problem answering; submit again later"
+		return diag
+	default:
+		diag.Category = DiagnosticAPI
+		return diag
+	}
+
+	if entry.ScryfallID != "" || entry.MTGOID != 0 {
+		return diag
+	}
+
+	// A failed set lookup counts as a known set, so a network error isn't reported as a bad set code
+	if entry.Set != "" {
+		if exists, err := source.SetExists(entry.Set); err == nil && !exists {
+			diag.Category = DiagnosticUnknownSet
+			diag.Suggestion = fmt.Sprintf("%q is not a Scryfall set code; correct it or leave it out to match the card by name", entry.Set)
+			return diag
+		}
+	}
+
+	if name, _ := source.SuggestName(entry.Name); name != "" && !strings.EqualFold(name, entry.Name) {
+		diag.Suggestion = fmt.Sprintf("Did you mean %q?", name)
+	} else if entry.CollectorNumber != "" {
+		diag.Suggestion = fmt.Sprintf("Check the collector number, or leave it out to use any printing from %q", entry.Set)
+	}
+	return diag
+}
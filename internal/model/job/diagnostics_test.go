@@ -0,0 +1,58 @@
+package job
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"Grimoire/internal/fakescryfall"
+)
+
+func TestLookupDiagnostic(t *testing.T) {
+	srv := fakescryfall.Start(fakescryfall.DefaultCards())
+	defer srv.Close()
+	source := NewScryfall(srv.URL, nil)
+
+	notFound := &APIError{StatusCode: http.StatusNotFound}
+	tests := []struct {
+		name       string
+		entry      DeckEntry
+		err        error
+		category   DiagnosticCategory
+		suggestion string
+	}{
+		{"misspelt name", DeckEntry{Name: "Lightnig Bolt"}, notFound, DiagnosticNotFound, `Did you mean "Lightning Bolt"?`},
+		{"unknown set", DeckEntry{Name: "Lightning Bolt", Set: "zzz", CollectorNumber: "1"}, notFound, DiagnosticUnknownSet, `"zzz" is not a Scryfall set code; correct it or leave it out to match the card by name`},
+		{"bad collector number", DeckEntry{Name: "Lightning Bolt", Set: "m11", CollectorNumber: "999"}, notFound, DiagnosticNotFound, `Check the collector number, or leave it out to use any printing from "m11"`},
+		{"rate limited", DeckEntry{Name: "Opt"}, fmt.Errorf("API rate limited after 3 attempts: %w", &APIError{StatusCode: http.StatusTooManyRequests}), DiagnosticRateLimited, "Scryfall is rate limiting requests; wait a minute and submit again"},
+		{"unreachable", DeckEntry{Name: "Opt"}, fmt.Errorf("HTTP request failed after 3 attempts: %w", &url.Error{Op: "Get", URL: "https://api.scryfall.com", Err: errors.New("connection refused")}), DiagnosticNetwork, "Scryfall could not be reached; check the connection and submit again"},
+		{"server error", DeckEntry{Name: "Opt"}, &APIError{StatusCode: http.StatusBadGateway}, DiagnosticAPI, "Scryfall had a problem answering; submit again later"},
+		{"rejected request", DeckEntry{Name: "Opt"}, &APIError{StatusCode: http.StatusUnprocessableEntity, Details: "Invalid query."}, DiagnosticAPI, ""},
+		{"unreadable response", DeckEntry{Name: "Opt"}, fmt.Errorf("JSON decode failed: %w", io.ErrUnexpectedEOF), DiagnosticAPI, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diag := lookupDiagnostic(tt.entry, tt.err, source)
+			if diag.Category != tt.category || diag.Suggestion != tt.suggestion {
+				t.Errorf("got %s %q, want %s %q", diag.Category, diag.Suggestion, tt.category, tt.suggestion)
+			}
+			if diag.Message != tt.err.Error() {
+				t.Errorf("message = %q, want %q", diag.Message, tt.err.Error())
+			}
+		})
+	}
+}
+
+func TestAPIErrorDetails(t *testing.T) {
+	srv := fakescryfall.Start(fakescryfall.DefaultCards())
+	defer srv.Close()
+
+	_, err := NewScryfall(srv.URL, nil).Card(DeckEntry{Name: "Sol Ring", Set: "zzz", CollectorNumber: "1"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Details == "" {
+		t.Fatalf("got %v, want a 404 APIError with Scryfall's details", err)
+	}
+}
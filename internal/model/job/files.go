@@ -0,0 +1,42 @@
+package job
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic writes data to name in dir, replacing the file in one rename so
+// readers never see a partial file
+func writeFileAtomic(dir, name string, data []byte) error {
+	return replaceFile(dir, name, bytes.NewReader(data), nil)
+}
+
+// replaceFile copies r to a temporary file in dir and renames it over name once
+// check, if given, accepts the temporary file. The temporary file is removed when
+// any step fails.
+func replaceFile(dir, name string, r io.Reader, check func(path string) error) error {
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	// CreateTemp makes the file private, but nothing written here is secret
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o644)
+	}
+	if err == nil && check != nil {
+		err = check(tmp.Name())
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(dir, name))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
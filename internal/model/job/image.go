@@ -0,0 +1,214 @@
+package job
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"strings"
+)
+
+// BleedMode selects how the bleed area around each card is produced
+type BleedMode string
+
+const (
+	BleedFill   BleedMode = "fill"   // solid black rectangle behind the card
+	BleedWhite  BleedMode = "white"  // solid white rectangle behind the card, to save ink
+	BleedExtend BleedMode = "extend" // repeat the outermost pixels of the image
+	BleedMirror BleedMode = "mirror" // reflect the image across its edges
+)
+
+// ParseBleedMode validates a bleed mode from the submit API. An empty mode selects BleedFill.
+func ParseBleedMode(mode string) (BleedMode, error) {
+	switch BleedMode(strings.ToLower(strings.TrimSpace(mode))) {
+	case "", BleedFill:
+		return BleedFill, nil
+	case BleedWhite:
+		return BleedWhite, nil
+	case BleedExtend:
+		return BleedExtend, nil
+	case BleedMirror:
+		return BleedMirror, nil
+	}
+	return "", fmt.Errorf("unknown bleed mode %q", mode)
+}
+
+// generated reports whether the bleed is built from the card image itself
+func (m BleedMode) generated() bool {
+	return m == BleedExtend || m == BleedMirror
+}
+
+// InkMode selects how card images are toned to save ink
+type InkMode string
+
+const (
+	InkColor     InkMode = "color"     // images as downloaded
+	InkGrayscale InkMode = "grayscale" // images converted to grayscale
+	InkLow       InkMode = "low_ink"   // colours lightened to roughly half the ink coverage
+)
+
+// ParseInkMode validates an ink mode from the submit API. An empty mode selects InkColor.
+func ParseInkMode(mode string) (InkMode, error) {
+	switch InkMode(strings.ToLower(strings.TrimSpace(mode))) {
+	case "", InkColor:
+		return InkColor, nil
+	case InkGrayscale:
+		return InkGrayscale, nil
+	case InkLow:
+		return InkLow, nil
+	}
+	return "", fmt.Errorf("unknown ink mode %q", mode)
+}
+
+// lowInkCoverage is the fraction of the original ink coverage kept by InkLow
+const lowInkCoverage = 0.5
+
+// applyInk tones img in place according to mode
+func applyInk(img *image.RGBA, mode InkMode) {
+	if mode != InkGrayscale && mode != InkLow {
+		return
+	}
+
+	for i := 0; i+3 < len(img.Pix); i += 4 {
+		px := img.Pix[i : i+3 : i+3]
+		if mode == InkGrayscale {
+			// ITU-R BT.601 luma
+			y := uint8((299*uint32(px[0]) + 587*uint32(px[1]) + 114*uint32(px[2]) + 500) / 1000)
+			px[0], px[1], px[2] = y, y, y
+			continue
+		}
+		for c := range px {
+			px[c] = 255 - uint8(math.Round(float64(255-px[c])*lowInkCoverage))
+		}
+	}
+}
+
+// imageOptions controls the processing applied to card images before they are embedded
+type imageOptions struct {
+	bleedMode     BleedMode
+	bleedX        float64 // bleed width as a fraction of the card width
+	bleedY        float64 // bleed height as a fraction of the card height
+	squareCorners bool    // fill in the rounded corners of Scryfall images
+	ink           InkMode // toning applied after all other processing
+}
+
+// newImageOptions returns the image processing needed for opts
+func newImageOptions(opts PrintOptions) imageOptions {
+	imgOpts := imageOptions{
+		bleedMode:     opts.Bleed,
+		squareCorners: opts.SquareCorners,
+		ink:           opts.Ink,
+	}
+	if opts.Bleed.generated() && opts.Layout.Bleed > 0 {
+		imgOpts.bleedX = opts.Layout.Bleed / opts.Layout.CardW
+		imgOpts.bleedY = opts.Layout.Bleed / opts.Layout.CardH
+		// The bleed is built from the image's edges, so transparent rounded corners
+		// would carry into it and print as black wedges
+		imgOpts.squareCorners = true
+	}
+	return imgOpts
+}
+
+func (o imageOptions) needsProcessing() bool {
+	return o.squareCorners || (o.bleedMode.generated() && (o.bleedX > 0 || o.bleedY > 0))
+}
+
+// processImage applies the corner and bleed processing in opts to img
+func processImage(img image.Image, opts imageOptions) image.Image {
+	bounds := img.Bounds()
+	nrgba := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(nrgba, nrgba.Bounds(), img, bounds.Min, draw.Src)
+
+	if opts.squareCorners {
+		squareCorners(nrgba)
+	}
+
+	if opts.bleedMode.generated() {
+		bx := int(math.Round(opts.bleedX * float64(bounds.Dx())))
+		by := int(math.Round(opts.bleedY * float64(bounds.Dy())))
+		if bx > 0 || by > 0 {
+			nrgba = extendBleed(nrgba, bx, by, opts.bleedMode == BleedMirror)
+		}
+	}
+
+	return nrgba
+}
+
+// squareCorners fills the rounded corners of a card image with the colour of the
+// neighbouring border, so no transparent or black notches show when it is cut.
+// The corner radius is that of a real card, so flattened images are handled too.
+func squareCorners(img *image.NRGBA) {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	r := w / 20 // 1/8" radius on a 2.5" wide card
+	if r == 0 || 2*r+2 >= w || 2*r >= h {
+		return
+	}
+
+	for dy := 0; dy < r; dy++ {
+		for dx := 0; dx < r; dx++ {
+			// Distance from the corner's arc centre, measured from the pixel centre
+			fx, fy := float64(r-dx)-0.5, float64(r-dy)-0.5
+			outside := math.Hypot(fx, fy) > float64(r)
+
+			// Corners, each as (x, y, sample x) with the sample taken from the
+			// straight border just past the corner on the same row
+			for _, c := range [4][3]int{
+				{dx, dy, r + 2},
+				{w - 1 - dx, dy, w - 3 - r},
+				{dx, h - 1 - dy, r + 2},
+				{w - 1 - dx, h - 1 - dy, w - 3 - r},
+			} {
+				blendCornerPixel(img, c[0], c[1], c[2], outside)
+			}
+		}
+	}
+}
+
+// blendCornerPixel replaces the pixel at x, y with the one at sampleX on the same row.
+// Pixels inside the arc keep their own colour in proportion to their alpha.
+func blendCornerPixel(img *image.NRGBA, x, y, sampleX int, outside bool) {
+	i := img.PixOffset(x, y)
+	s := img.PixOffset(sampleX, y)
+	px, sample := img.Pix[i:i+4:i+4], img.Pix[s:s+4:s+4]
+
+	alpha := uint32(px[3])
+	if outside {
+		alpha = 0
+	}
+	if alpha == 255 {
+		return
+	}
+
+	for c := 0; c < 3; c++ {
+		px[c] = uint8((uint32(px[c])*alpha + uint32(sample[c])*(255-alpha)) / 255)
+	}
+	px[3] = 255
+}
+
+// extendBleed returns a copy of img with bx columns and by rows of bleed added on
+// every side, either repeating the edge pixels or mirroring the image across its edges
+func extendBleed(img *image.NRGBA, bx, by int, mirror bool) *image.NRGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	bx, by = min(bx, w-1), min(by, h-1)
+	out := image.NewNRGBA(image.Rect(0, 0, w+2*bx, h+2*by))
+
+	source := func(v, size int) int {
+		switch {
+		case v < 0 && mirror:
+			return -v - 1
+		case v >= size && mirror:
+			return 2*size - v - 1
+		}
+		return min(max(v, 0), size-1)
+	}
+
+	for y := 0; y < h+2*by; y++ {
+		sy := source(y-by, h)
+		for x := 0; x < w+2*bx; x++ {
+			sx := source(x-bx, w)
+			d, s := out.PixOffset(x, y), img.PixOffset(sx, sy)
+			copy(out.Pix[d:d+4], img.Pix[s:s+4])
+		}
+	}
+	return out
+}
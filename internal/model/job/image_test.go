@@ -0,0 +1,48 @@
+package job
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// roundedCard returns a card image of one colour with transparent rounded corners,
+// as Scryfall's PNG images have
+func roundedCard(w, h int, c color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	r := float64(w / 20)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			// Distance past the arc of the nearest corner
+			dx := max(r-float64(x)-0.5, float64(x)+0.5-(float64(w)-r), 0)
+			dy := max(r-float64(y)-0.5, float64(y)+0.5-(float64(h)-r), 0)
+			if math.Hypot(dx, dy) <= r {
+				img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+	return img
+}
+
+func TestProcessImageBleedCorners(t *testing.T) {
+	red := color.NRGBA{R: 200, A: 255}
+	layout, err := LayoutByName("mpc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mode := range []BleedMode{BleedExtend, BleedMirror} {
+		opts := newImageOptions(PrintOptions{Layout: layout, Bleed: mode})
+		img := processImage(roundedCard(200, 280, red), opts).(*image.NRGBA)
+
+		// The bleed corners and the card corners inside the trim take the border colour
+		w, h := img.Rect.Dx(), img.Rect.Dy()
+		bx, by := (w-200)/2, (h-280)/2
+		for _, p := range []image.Point{{0, 0}, {w - 1, 0}, {0, h - 1}, {w - 1, h - 1}, {bx, by}, {w - 1 - bx, h - 1 - by}} {
+			if got := img.NRGBAAt(p.X, p.Y); got != red {
+				t.Errorf("bleed=%s: pixel %v is %v, want %v", mode, p, got, red)
+			}
+		}
+	}
+}
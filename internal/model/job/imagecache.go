@@ -0,0 +1,193 @@
+package job
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ImageCacheStats counts the card images a job found in the image cache and the
+// ones it had to download
+type ImageCacheStats struct {
+	Hits   int `json:"hits"`
+	Misses int `json:"misses"`
+}
+
+// imageCache keeps card images on disk, already converted for the PDF, so each
+// image is downloaded and converted once. Files are named after a hash of the
+// image URI and the processing applied; Scryfall image URIs name the set,
+// collector number, language, face and image version. The least recently used
+// images are evicted once the cache grows past maxBytes.
+type imageCache struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	size    int64
+	entries map[string]*list.Element // file name to its element in lru
+	lru     *list.List               // *cacheEntry, most recently used first
+}
+
+// cacheEntry is one cached image file
+type cacheEntry struct {
+	name string
+	size int64
+}
+
+var (
+	cardImageCache *imageCache // nil when caching is disabled
+	imageCacheMu   sync.RWMutex
+)
+
+// InitImageCache stores converted card images in dir, keeping at most maxBytes of
+// them. Images already in dir are kept, oldest first in line for eviction. A
+// maxBytes of zero or less disables the cache.
+func InitImageCache(dir string, maxBytes int64) error {
+	if maxBytes <= 0 {
+		imageCacheMu.Lock()
+		defer imageCacheMu.Unlock()
+		cardImageCache = nil
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create image cache directory: %w", err)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read image cache directory: %w", err)
+	}
+	type cachedFile struct {
+		entry   cacheEntry
+		modTime time.Time
+	}
+	var cached []cachedFile
+	for _, file := range files {
+		if strings.HasPrefix(file.Name(), ".tmp-") {
+			os.Remove(filepath.Join(dir, file.Name())) // left by an interrupted write
+			continue
+		}
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".jpg") {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		cached = append(cached, cachedFile{cacheEntry{file.Name(), info.Size()}, info.ModTime()})
+	}
+	// Hits touch the file, so the newest files were used most recently
+	sort.Slice(cached, func(i, j int) bool { return cached[i].modTime.After(cached[j].modTime) })
+
+	c := &imageCache{dir: dir, maxBytes: maxBytes, entries: make(map[string]*list.Element), lru: list.New()}
+	for _, file := range cached {
+		entry := file.entry
+		c.entries[entry.name] = c.lru.PushBack(&entry)
+		c.size += entry.size
+	}
+	c.mu.Lock()
+	c.evict()
+	c.mu.Unlock()
+	log.Printf("Image cache in %s holds %d images (%d of %d bytes)", dir, c.lru.Len(), c.size, maxBytes)
+
+	imageCacheMu.Lock()
+	defer imageCacheMu.Unlock()
+	cardImageCache = c
+	return nil
+}
+
+func getImageCache() *imageCache {
+	imageCacheMu.RLock()
+	defer imageCacheMu.RUnlock()
+	return cardImageCache
+}
+
+// imageCacheKey returns the cache file name for the image at uri processed with opts
+func imageCacheKey(uri string, opts imageOptions) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\n%+v", uri, opts)))
+	return hex.EncodeToString(sum[:]) + ".jpg"
+}
+
+// get returns the cached image with the given key
+func (c *imageCache) get(key string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	elem, ok := c.entries[key]
+	if ok {
+		c.lru.MoveToFront(elem)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	path := filepath.Join(c.dir, key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		// Evicted since, or removed from the directory by hand
+		c.remove(key)
+		return nil, false
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return data, true
+}
+
+// put stores an image under key, evicting the least recently used images to make
+// room. Images bigger than the whole cache, or that can't be written, are left out
+// of the cache.
+func (c *imageCache) put(key string, data []byte) {
+	if c == nil || int64(len(data)) > c.maxBytes {
+		return
+	}
+	if err := writeFileAtomic(c.dir, key, data); err != nil {
+		log.Printf("Failed to cache image: %v", err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.size -= elem.Value.(*cacheEntry).size
+		c.lru.Remove(elem)
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{name: key, size: int64(len(data))})
+	c.size += int64(len(data))
+	c.evict()
+}
+
+// remove drops key from the cache index
+func (c *imageCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.size -= elem.Value.(*cacheEntry).size
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+	}
+}
+
+// evict deletes the least recently used images until the cache fits in maxBytes.
+// c.mu must be held.
+func (c *imageCache) evict() {
+	for c.size > c.maxBytes && c.lru.Len() > 0 {
+		elem := c.lru.Back()
+		entry := elem.Value.(*cacheEntry)
+		if err := os.Remove(filepath.Join(c.dir, entry.name)); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to evict cached image %s: %v", entry.name, err)
+		}
+		c.size -= entry.size
+		c.lru.Remove(elem)
+		delete(c.entries, entry.name)
+	}
+}
@@ -0,0 +1,91 @@
+package job
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// useImageCache caches images in a temporary directory for the rest of the test
+func useImageCache(t *testing.T, maxBytes int64) (*imageCache, string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := InitImageCache(dir, maxBytes); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { InitImageCache("", 0) })
+	return getImageCache(), dir
+}
+
+func TestImageCacheEviction(t *testing.T) {
+	c, dir := useImageCache(t, 250)
+	image := func(b byte) []byte { return bytes.Repeat([]byte{b}, 100) }
+
+	steps := []struct {
+		name string
+		get  string // key read before the put, making it the most recently used
+		put  string
+		data []byte
+		want []string // files left on disk, in name order
+	}{
+		{"first", "", "a.jpg", image('a'), []string{"a.jpg"}},
+		{"second", "", "b.jpg", image('b'), []string{"a.jpg", "b.jpg"}},
+		{"evicts the oldest", "", "c.jpg", image('c'), []string{"b.jpg", "c.jpg"}},
+		{"evicts the least recently used", "b.jpg", "d.jpg", image('d'), []string{"b.jpg", "d.jpg"}},
+		{"rejects an image bigger than the cache", "", "e.jpg", bytes.Repeat([]byte{'e'}, 300), []string{"b.jpg", "d.jpg"}},
+	}
+	for _, step := range steps {
+		if step.get != "" {
+			if _, ok := c.get(step.get); !ok {
+				t.Fatalf("%s: %s isn't cached", step.name, step.get)
+			}
+		}
+		c.put(step.put, step.data)
+
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		var size int64
+		for _, file := range files {
+			info, err := file.Info()
+			if err != nil {
+				t.Fatal(err)
+			}
+			names = append(names, file.Name())
+			size += info.Size()
+		}
+		if !reflect.DeepEqual(names, step.want) || len(c.entries) != len(step.want) {
+			t.Errorf("%s: cache holds %v (%d indexed), want %v", step.name, names, len(c.entries), step.want)
+		}
+		if size > c.maxBytes || c.size != size {
+			t.Errorf("%s: cache holds %d bytes (%d counted), want at most %d", step.name, size, c.size, c.maxBytes)
+		}
+	}
+}
+
+func TestInitImageCacheEvicts(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.jpg", "b.jpg", "c.jpg"} {
+		if err := os.WriteFile(filepath.Join(dir, name), bytes.Repeat([]byte{name[0]}, 100), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(dir, ".tmp-123"), []byte("partial"), 0o644)
+
+	if err := InitImageCache(dir, 250); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { InitImageCache("", 0) })
+
+	files, _ := os.ReadDir(dir)
+	if len(files) != 2 {
+		t.Errorf("cache directory holds %v, want two images", files)
+	}
+	if c := getImageCache(); c.size != 200 || c.lru.Len() != 2 {
+		t.Errorf("cache holds %d images of %d bytes, want 2 of 200", c.lru.Len(), c.size)
+	}
+}
@@ -4,16 +4,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"image"
 	"image/draw"
 	"image/jpeg"
+	_ "image/png" // Scryfall images and uploaded card backs are PNGs
 	"io"
 	"log"
 	"net/http"
-	"regexp"
 	"runtime"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -22,7 +22,6 @@ import (
 	"github.com/golang-queue/queue/core"
 	"github.com/golang-queue/queue/job"
 	"github.com/google/uuid"
-	"github.com/signintech/gopdf"
 )
 
 // Global queue and job storage
@@ -31,18 +30,74 @@ var jobs sync.Map
 
 // GrimoireJob represents a decklist processing job
 type GrimoireJob struct {
-	ID        string
-	Status    string        // "queued", "parse", "fetch", "generate", "complete", "error"
-	PDF       *bytes.Buffer // Store the generated PDF
-	Error     error
-	CreatedAt time.Time
-	mu        sync.RWMutex
+	ID          string
+	Status      string           // "queued", "parse", "fetch", "generate", "complete", "error"
+	PDF         *bytes.Buffer    // Store the generated PDF
+	Warnings    []Warning        // Card images that were replaced by text proxies
+	Cards       []ResolvedCard   // Printings the decklist lines resolved to
+	Diagnostics []Diagnostic     // Decklist lines that couldn't be resolved
+	ImageCache  *ImageCacheStats // Card images found in the image cache, nil until images are fetched
+	Error       error
+	CreatedAt   time.Time
+	mu          sync.RWMutex
+}
+
+// JobOptions holds the per-job settings chosen at submit time
+type JobOptions struct {
+	Layout       string   `json:"layout"`               // layout preset name, see LayoutPresets
+	Gutter       *float64 `json:"gutter,omitempty"`     // overrides the preset gutter, in points
+	CutLines     *bool    `json:"cut_lines,omitempty"`  // overrides the preset crop marks with cut lines
+	CropMarks    string   `json:"crop_marks,omitempty"` // overrides the preset crop marks, see CropMarks
+	Registration bool     `json:"registration"`         // add print-and-cut registration marks
+	Duplex       bool     `json:"duplex"`               // print a mirrored page of card backs after each page
+	BackURL      string   `json:"back_url,omitempty"`   // generic card back image, defaults to the card source's BackURL
+	BackID       string   `json:"back_id,omitempty"`    // stored card back, takes precedence over BackURL
+
+	Bleed         string   `json:"bleed,omitempty"`      // "fill" (default), "white", "extend" or "mirror", see BleedMode
+	BleedSize     *float64 `json:"bleed_size,omitempty"` // overrides the preset bleed, in points
+	SquareCorners bool     `json:"square_corners"`       // fill in the rounded corners of card images
+	Ink           string   `json:"ink,omitempty"`        // "color" (default), "grayscale" or "low_ink", see InkMode
+
+	Format        string     `json:"format,omitempty"`         // decklist format, detected when empty, see DecklistFormat
+	CSVColumns    CSVColumns `json:"csv_columns,omitempty"`    // column mapping for the CSV format
+	Order         string     `json:"order,omitempty"`          // card order in the PDF, see CardOrder
+	Printing      string     `json:"printing,omitempty"`       // printing for cards named without a collector number, see PrintingMode
+	PreferredSets []string   `json:"preferred_sets,omitempty"` // set codes in order of preference for PrintingPreferred
+	Sections      []string   `json:"sections,omitempty"`       // decklist sections to print, see SectionFilter
+	Render        string     `json:"render,omitempty"`         // "image" (default) or "text", see RenderMode
+	Strict        bool       `json:"strict"`                   // fail the job when a card image can't be loaded
+	Related       bool       `json:"related"`                  // add the tokens, emblems and meld partners the cards need
+	SkipInvalid   bool       `json:"skip_invalid"`             // leave out lines that can't be resolved instead of failing the job
+}
+
+// Validate checks that the options describe a job that can be run
+func (o JobOptions) Validate() error {
+	if _, err := o.ResolvePrintOptions(); err != nil {
+		return err
+	}
+	if _, err := ParseCardOrder(o.Order); err != nil {
+		return err
+	}
+	if _, err := ParseDecklistFormat(o.Format); err != nil {
+		return err
+	}
+	if err := o.CSVColumns.Validate(); err != nil {
+		return err
+	}
+	if _, err := o.ResolvePrintingPreference(); err != nil {
+		return err
+	}
+	if err := validateBackURL(o.BackURL); err != nil {
+		return err
+	}
+	return nil
 }
 
 // DecklistTask is the enqueued task payload
 type DecklistTask struct {
-	JobID    string `json:"job_id"`
-	Decklist string `json:"decklist"`
+	JobID    string     `json:"job_id"`
+	Decklist string     `json:"decklist"`
+	Options  JobOptions `json:"options"`
 }
 
 func (dt *DecklistTask) Bytes() []byte {
@@ -56,12 +111,64 @@ func (dt *DecklistTask) Bytes() []byte {
 
 // Card represents a Magic: The Gathering card
 type Card struct {
+	ID              string `json:"id"`
 	Quantity        int
 	Name            string
 	Set             string
-	CollectorNumber string
+	CollectorNumber string `json:"collector_number"`
+	Lang            string `json:"lang"`
+	OracleID        string `json:"oracle_id"`
+	MTGOID          int    `json:"mtgo_id"`
+	MTGOFoilID      int    `json:"mtgo_foil_id"`
+	Finish          string `json:"-"` // "foil" or "etched" when marked in the decklist
+	Section         string // decklist section the card was listed under, empty if none
 	Layout          string `json:"layout"`
+	SetType         string `json:"set_type"`  // "expansion", "memorabilia", "token" and so on
+	Oversized       bool   `json:"oversized"` // printed larger than a normal card
 	ImageURIs       map[string]string
+	AllParts        []RelatedCard `json:"all_parts"` // tokens, emblems and meld pieces related to the card
+	ReleasedAt      string        `json:"released_at"`
+	Prices          CardPrices    `json:"prices"`
+	Games           []string      `json:"games"` // "paper", "mtgo" and "arena"
+
+	ManaCost   string     `json:"mana_cost"`
+	TypeLine   string     `json:"type_line"`
+	OracleText string     `json:"oracle_text"`
+	Power      string     `json:"power"`
+	Toughness  string     `json:"toughness"`
+	Loyalty    string     `json:"loyalty"`
+	Colors     []string   `json:"colors"`
+	CardFaces  []CardFace `json:"card_faces"`
+}
+
+// CardPrices holds a printing's market prices
+type CardPrices struct {
+	USD string `json:"usd"`
+}
+
+// ResolvedCard records the printing a decklist line resolved to
+type ResolvedCard struct {
+	Line            int    `json:"line"` // 0 for related cards added by the job
+	Input           string `json:"input"`
+	Quantity        int    `json:"quantity"`
+	Name            string `json:"name"`
+	Set             string `json:"set"`
+	CollectorNumber string `json:"collector_number"`
+	Section         string `json:"section,omitempty"`
+	Layout          string `json:"layout"`
+	Faces           int    `json:"faces"` // number of card faces, 1 for single-faced cards
+}
+
+// CardFace is one face of a multi-faced card
+type CardFace struct {
+	Name       string   `json:"name"`
+	ManaCost   string   `json:"mana_cost"`
+	TypeLine   string   `json:"type_line"`
+	OracleText string   `json:"oracle_text"`
+	Power      string   `json:"power"`
+	Toughness  string   `json:"toughness"`
+	Loyalty    string   `json:"loyalty"`
+	Colors     []string `json:"colors"`
 }
 
 // Rate limiter variables
@@ -105,12 +212,16 @@ func Shutdown() {
 }
 
 // CreateJob creates a job and enqueues it with per-task timeout
-func CreateJob(decklist string) (*GrimoireJob, error) {
+func CreateJob(decklist string, options JobOptions) (*GrimoireJob, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
 	jobInstance := NewGrimoireJob()
 	jobs.Store(jobInstance.ID, jobInstance)
 
 	// Enqueue task with 2-minute per-task timeout
-	task := &DecklistTask{JobID: jobInstance.ID, Decklist: decklist}
+	task := &DecklistTask{JobID: jobInstance.ID, Decklist: decklist, Options: options}
 	opts := []job.AllowOption{
 		{Timeout: job.Time(2 * time.Minute)},
 	}
@@ -210,6 +321,30 @@ func (j *GrimoireJob) setPDF(pdf *bytes.Buffer) {
 	j.PDF = pdf
 }
 
+func (j *GrimoireJob) setWarnings(warnings []Warning) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.Warnings = warnings
+}
+
+func (j *GrimoireJob) setImageCache(stats ImageCacheStats) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.ImageCache = &stats
+}
+
+func (j *GrimoireJob) setCards(cards []ResolvedCard) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.Cards = cards
+}
+
+func (j *GrimoireJob) setDiagnostics(diagnostics []Diagnostic) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.Diagnostics = diagnostics
+}
+
 func (j *GrimoireJob) GetStatus() (string, error) {
 	j.mu.RLock()
 	defer j.mu.RUnlock()
@@ -222,6 +357,30 @@ func (j *GrimoireJob) GetPDF() *bytes.Buffer {
 	return j.PDF
 }
 
+func (j *GrimoireJob) GetWarnings() []Warning {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.Warnings
+}
+
+func (j *GrimoireJob) GetImageCache() *ImageCacheStats {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.ImageCache
+}
+
+func (j *GrimoireJob) GetCards() []ResolvedCard {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.Cards
+}
+
+func (j *GrimoireJob) GetDiagnostics() []Diagnostic {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.Diagnostics
+}
+
 // ProcessDecklistHandler is the queue task handler
 func ProcessDecklistHandler(ctx context.Context, m core.TaskMessage) error {
 	var dt DecklistTask
@@ -235,214 +394,282 @@ func ProcessDecklistHandler(ctx context.Context, m core.TaskMessage) error {
 		return fmt.Errorf("job %s not found", dt.JobID)
 	}
 
+	printOptions, err := dt.Options.ResolvePrintOptions()
+	if err != nil {
+		job.setError(err)
+		return err
+	}
+
+	format, err := ParseDecklistFormat(dt.Options.Format)
+	if err != nil {
+		job.setError(err)
+		return err
+	}
+
+	printing, err := dt.Options.ResolvePrintingPreference()
+	if err != nil {
+		job.setError(err)
+		return err
+	}
+
 	job.setStatus("parse")
 
 	// Use decklist from task payload
-	decklist := strings.ReplaceAll(dt.Decklist, "\r\n", "\n")
-	decklist = strings.ReplaceAll(decklist, "\r", "\n")
-
-	lines := strings.Split(decklist, "\n")
-	log.Printf("Job %s: Parsing %d lines", dt.JobID, len(lines))
-
-	var nonEmptyLines []string
-	for i, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		if trimmed != "" {
-			nonEmptyLines = append(nonEmptyLines, line)
-		} else {
-			log.Printf("Job %s: Filtering out empty line %d: %q", dt.JobID, i+1, line)
-		}
+	format, entries, parseErrors := ParseDecklist(dt.Decklist, format, ParseOptions{CSVColumns: dt.Options.CSVColumns})
+	log.Printf("Job %s: Parsing decklist as %s", dt.JobID, format)
+	log.Printf("Job %s: Parsed %d card lines", dt.JobID, len(entries))
+
+	// Lines in sections that won't be printed don't fail the job, even if they can't be parsed
+	sections := ParseSections(dt.Options.Sections)
+	if err := sections.check(entries, parseErrors); err != nil {
+		job.setError(err)
+		return err
+	}
+	parsed := len(entries)
+	entries = filterSections(entries, sections)
+
+	var diagnostics []Diagnostic
+	for _, err := range filterParseErrors(parseErrors, sections) {
+		diagnostics = append(diagnostics, parseDiagnostic(err))
+	}
+	if len(diagnostics) > 0 && !dt.Options.SkipInvalid {
+		job.setDiagnostics(diagnostics)
+		err := fmt.Errorf("%d decklist line(s) could not be parsed", len(diagnostics))
+		job.setError(err)
+		return err
+	}
+
+	// A job without cards has no PDF to offer, so it fails like one whose lookups all failed
+	if parsed == 0 {
+		err := fmt.Errorf("the decklist has no valid cards")
+		job.setDiagnostics(diagnostics)
+		job.setError(err)
+		return err
+	}
+	if len(entries) == 0 {
+		err := fmt.Errorf("the decklist has no cards in the selected sections")
+		job.setDiagnostics(diagnostics)
+		job.setError(err)
+		return err
 	}
 
-	log.Printf("Job %s: After filtering: %d non-empty lines", dt.JobID, len(nonEmptyLines))
+	cards, resolved, lookupDiagnostics := lookupCards("Job "+dt.JobID, entries, printing)
+	diagnostics = append(diagnostics, lookupDiagnostics...)
+	sortDiagnostics(diagnostics)
+	job.setCards(resolved)
+	job.setDiagnostics(diagnostics)
 
-	if len(nonEmptyLines) == 0 {
-		job.setStatus("complete")
-		return nil
+	if len(diagnostics) > 0 && (!dt.Options.SkipInvalid || len(cards) == 0) {
+		err := fmt.Errorf("%d decklist line(s) could not be resolved", len(diagnostics))
+		job.setError(err)
+		return err
+	}
+	if len(diagnostics) > 0 {
+		log.Printf("Job %s: Skipping %d decklist lines that could not be resolved", dt.JobID, len(diagnostics))
 	}
 
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+	order, err := ParseCardOrder(dt.Options.Order)
+	if err != nil {
+		job.setError(err)
+		return err
+	}
+	SortCards(cards, order)
+
+	if dt.Options.Related {
+		related := relatedCards("Job "+dt.JobID, cards)
+		for _, card := range related {
+			resolved = append(resolved, resolvedCard(DeckEntry{}, card))
+		}
+		job.setCards(resolved)
+		cards = append(cards, related...)
+	}
+
+	job.setStatus("fetch")
+
+	job.setStatus("generate")
+	result, err := GeneratePDF(cards, printOptions)
+	if result != nil {
+		job.setWarnings(result.Warnings)
+		job.setImageCache(result.ImageCache)
+	}
+	if err != nil {
+		job.setError(fmt.Errorf("PDF generation failed: %w", err))
+		return err
 	}
 
+	job.setPDF(result.PDF)
+	job.setStatus("complete")
+
+	return nil
+}
+
+// lookupCards looks every decklist entry up on Scryfall, retrying failed lookups.
+// Cards come back in decklist order; entries that can't be found are described by
+// diagnostics instead. label prefixes the log lines.
+func lookupCards(label string, entries []DeckEntry, printing PrintingPreference) ([]Card, []ResolvedCard, []Diagnostic) {
+	source := getCardSource()
+
 	maxConcurrent := 1
 	semaphore := make(chan struct{}, maxConcurrent)
 
-	var wg sync.WaitGroup
-	resultsChan := make(chan struct {
-		card Card
-		err  error
-	}, len(nonEmptyLines))
+	// Results are stored by line so cards come out in decklist order,
+	// whatever order the lookups finish in
+	results := make([]lookupResult, len(entries))
 
+	// Most cards are found in a few batched requests; the rest are looked up one by one
+	pending := collectCards(label, entries, results, source, printing)
+
+	var wg sync.WaitGroup
 	var cardsCompleted int
 	var mu sync.Mutex
 
-	for _, line := range nonEmptyLines {
+	for _, i := range pending {
+		entry := entries[i]
 		wg.Add(1)
-		go func(line string) {
+		go func(i int, entry DeckEntry) {
 			defer wg.Done()
 
 			semaphore <- struct{}{}
 			defer func() { <-semaphore }()
 
-			// Manual retry for ParseCard
+			// Manual retry for FetchCard
 			var card Card
 			var err error
 			for attempt := 0; attempt < 3; attempt++ {
-				card, err = ParseCard(line, client)
+				card, err = FetchCard(entry, source, printing)
 				if err == nil {
 					break
 				}
-				log.Printf("Job %s: Parse attempt %d failed for %q: %v", dt.JobID, attempt+1, line, err)
+				log.Printf("%s: Lookup attempt %d failed for %q: %v", label, attempt+1, entry.Raw, err)
+				var apiErr *APIError
+				if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+					break // retrying won't find a card that doesn't exist
+				}
 				if attempt < 2 {
 					time.Sleep(time.Second * time.Duration(attempt+1)) // Exponential backoff
 				}
 			}
 
 			if err != nil {
-				log.Printf("Job %s: Failed to parse line: %q, error: %v", dt.JobID, line, err)
-				resultsChan <- struct {
-					card Card
-					err  error
-				}{card: Card{}, err: fmt.Errorf("failed to parse %q: %w", line, err)}
+				log.Printf("%s: Failed to look up line %d: %q, error: %v", label, entry.Line, entry.Raw, err)
+				results[i] = lookupResult{err: err}
 				return
 			}
 
-			log.Printf("Job %s: Successfully parsed card: %s (Set: %s, Collector: %s)", dt.JobID, card.Name, card.Set, card.CollectorNumber)
+			log.Printf("%s: Successfully parsed card: %s (Set: %s, Collector: %s)", label, card.Name, card.Set, card.CollectorNumber)
 
 			mu.Lock()
 			cardsCompleted++
-			log.Printf("Job %s: Parsed card: %s (%d / %d cards completed)", dt.JobID, card.Name, cardsCompleted, len(nonEmptyLines))
+			log.Printf("%s: Parsed card: %s (%d / %d cards completed)", label, card.Name, cardsCompleted, len(pending))
 			mu.Unlock()
 
-			resultsChan <- struct {
-				card Card
-				err  error
-			}{card: card, err: nil}
-		}(line)
+			results[i] = lookupResult{card: card}
+		}(i, entry)
 	}
-
-	go func() {
-		wg.Wait()
-		close(resultsChan)
-	}()
+	wg.Wait()
 
 	var cards []Card
-	var errors []error
-	for res := range resultsChan {
+	var resolved []ResolvedCard
+	var diagnostics []Diagnostic
+	for i, res := range results {
 		if res.err != nil {
-			errors = append(errors, res.err)
-		} else {
-			cards = append(cards, res.card)
+			diagnostics = append(diagnostics, lookupDiagnostic(entries[i], res.err, source))
+			continue
 		}
+		cards = append(cards, res.card)
+		resolved = append(resolved, resolvedCard(entries[i], res.card))
 	}
+	return cards, resolved, diagnostics
+}
 
-	if len(errors) > 0 {
-		err := fmt.Errorf("encountered %d errors: %v", len(errors), errors)
-		job.setError(err)
-		return err
+// resolvedCard records the printing entry resolved to
+func resolvedCard(entry DeckEntry, card Card) ResolvedCard {
+	faces := max(len(card.CardFaces), 1)
+	return ResolvedCard{
+		Line:            entry.Line,
+		Input:           strings.TrimSpace(entry.Raw),
+		Quantity:        card.Quantity,
+		Name:            card.Name,
+		Set:             card.Set,
+		CollectorNumber: card.CollectorNumber,
+		Section:         card.Section,
+		Layout:          card.Layout,
+		Faces:           faces,
 	}
+}
 
-	job.setStatus("fetch")
-
-	job.setStatus("generate")
-	pdfBuffer, err := GeneratePDF(cards)
+// ParseCard parses a single Archidekt decklist line and looks the card up in source
+func ParseCard(line string, source CardSource) (Card, error) {
+	entry, err := parseArchidektLine(line)
 	if err != nil {
-		job.setError(fmt.Errorf("PDF generation failed: %w", err))
-		return err
+		return Card{}, err
 	}
-
-	job.setPDF(pdfBuffer)
-	job.setStatus("complete")
-
-	return nil
+	return FetchCard(entry, source, PrintingPreference{})
 }
 
-func ParseCard(line string, client *http.Client) (Card, error) {
-	re := regexp.MustCompile(`^(\d+)\s+(.+?)\s+\(([^)]+)\)\s+([^\s\r\n]+)$`)
-
-	line = strings.TrimSpace(line)
-	if line == "" {
-		return Card{}, fmt.Errorf("empty line")
+// FetchCard looks up the printing named by a decklist entry in source. Entries
+// with a Scryfall ID or MTGO catalog ID resolve to that printing. Entries without a
+// collector number are matched by fuzzy name, within their set when one is given,
+// and resolve to the printing chosen by pref, as do entries with a NameFallback
+// whose printing isn't found. Non-English printings need a set and collector number.
+func FetchCard(entry DeckEntry, source CardSource, pref PrintingPreference) (Card, error) {
+	card, err := source.Card(entry)
+	var apiErr *APIError
+	if named, ok := entry.byName(); ok && errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		entry = named
+		card, err = source.Card(entry)
 	}
-
-	matches := re.FindStringSubmatch(line)
-	if matches == nil {
-		fallbackRe := regexp.MustCompile(`^(\d+)\s+(.+?)\s+\(([^)]+)\)\s+(.+)$`)
-		matches = fallbackRe.FindStringSubmatch(line)
-		if matches == nil {
-			return Card{}, fmt.Errorf("could not parse line: %q", line)
-		}
-		matches[4] = strings.TrimSpace(matches[4])
-	}
-
-	quantity, err := strconv.Atoi(matches[1])
 	if err != nil {
-		return Card{}, fmt.Errorf("invalid quantity: %w", err)
+		return Card{}, err
 	}
-
-	card := Card{
-		Quantity:        quantity,
-		Name:            strings.TrimSpace(matches[2]),
-		Set:             matches[3],
-		CollectorNumber: matches[4],
+	if err := completeCard(&card, entry, pref, source); err != nil {
+		return Card{}, err
 	}
+	return card, nil
+}
 
-	maxRetries := 3
-	baseDelay := 100 * time.Millisecond
-
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		rateLimitWait()
-
-		url := fmt.Sprintf("https://api.scryfall.com/cards/%s/%s", card.Set, card.CollectorNumber)
-		resp, err := client.Get(url)
-		if err != nil {
-			if attempt == maxRetries-1 {
-				return Card{}, fmt.Errorf("HTTP request failed after %d attempts: %w", maxRetries, err)
-			}
-			time.Sleep(baseDelay * time.Duration(1<<attempt))
-			continue
-		}
-
-		if resp.StatusCode == http.StatusTooManyRequests {
-			resp.Body.Close()
-			if attempt == maxRetries-1 {
-				return Card{}, fmt.Errorf("API rate limited after %d attempts", maxRetries)
-			}
-			delay := 5 * time.Second * time.Duration(1<<attempt)
-			log.Printf("Rate limited, waiting %v before retry %d/%d", delay, attempt+2, maxRetries+1)
-			time.Sleep(delay)
-			continue
-		}
+// byName returns entry without its printing when the entry falls back to a name lookup
+func (e DeckEntry) byName() (DeckEntry, bool) {
+	if !e.NameFallback || e.Name == "" {
+		return e, false
+	}
+	e.MTGOID, e.Set, e.CollectorNumber, e.NameFallback = 0, "", "", false
+	return e, true
+}
 
-		if resp.StatusCode != http.StatusOK {
-			resp.Body.Close()
-			return Card{}, fmt.Errorf("API error: status %d", resp.StatusCode)
+// completeCard adds the decklist details of entry to the card source returned for
+// it, moves cards named without a collector number to the preferred printing, and
+// sets the image URIs
+func completeCard(card *Card, entry DeckEntry, pref PrintingPreference, source CardSource) error {
+	card.Quantity = entry.Quantity
+	card.Finish = entry.Finish
+	card.Section = entry.Section
+
+	if entry.CollectorNumber == "" && entry.ScryfallID == "" && entry.MTGOID == 0 {
+		if err := preferPrinting(card, entry.Set, pref, source); err != nil {
+			return err
 		}
+	}
 
-		if err := json.NewDecoder(resp.Body).Decode(&card); err != nil {
-			resp.Body.Close()
-			return Card{}, fmt.Errorf("JSON decode failed: %w", err)
-		}
-		resp.Body.Close()
+	card.ImageURIs = source.ImageURIs(card)
+	return nil
+}
 
-		break
-	}
+// APIError is a Scryfall response with an unexpected status code. Card sources
+// report cards they don't have as a 404.
+type APIError struct {
+	StatusCode int
+	Details    string // Scryfall's explanation of the error, when it sent one
+}
 
-	if card.Layout == "transform" || card.Layout == "modal_dfc" {
-		card.ImageURIs = map[string]string{
-			"front": fmt.Sprintf("https://api.scryfall.com/cards/%s/%s?format=image&version=png", card.Set, card.CollectorNumber),
-			"back":  fmt.Sprintf("https://api.scryfall.com/cards/%s/%s?format=image&version=png&face=back", card.Set, card.CollectorNumber),
-		}
-	} else {
-		card.ImageURIs = map[string]string{
-			"front": fmt.Sprintf("https://api.scryfall.com/cards/%s/%s?format=image&version=png", card.Set, card.CollectorNumber),
-		}
+func (e *APIError) Error() string {
+	if e.Details != "" {
+		return fmt.Sprintf("API error: status %d: %s", e.StatusCode, e.Details)
 	}
-
-	return card, nil
+	return fmt.Sprintf("API error: status %d", e.StatusCode)
 }
 
+// FetchImageWithRetry downloads an image, retrying failures and rate limiting
 func FetchImageWithRetry(uri string, maxRetries int) ([]byte, error) {
 	var lastErr error
 
@@ -455,7 +682,7 @@ func FetchImageWithRetry(uri string, maxRetries int) ([]byte, error) {
 
 		rateLimitWait()
 
-		resp, err := http.Get(uri)
+		resp, err := getImageClient().Get(uri)
 		if err != nil {
 			lastErr = err
 			log.Printf("Image fetch attempt %d failed for %s: %v", attempt+1, uri, err)
@@ -493,20 +720,26 @@ func FetchImageWithRetry(uri string, maxRetries int) ([]byte, error) {
 	return nil, fmt.Errorf("failed to fetch image after %d attempts: %w", maxRetries+1, lastErr)
 }
 
-// convertTo8Bit converts a 16-bit image to 8-bit for gopdf compatibility
-func convertTo8Bit(imageData []byte) ([]byte, error) {
+// convertTo8Bit converts a 16-bit image to 8-bit for gopdf compatibility,
+// applying the corner and bleed processing selected in opts along the way
+func convertTo8Bit(imageData []byte, opts imageOptions) ([]byte, error) {
 	// Decode the image
 	img, _, err := image.Decode(bytes.NewReader(imageData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode image: %w", err)
 	}
 
+	if opts.needsProcessing() {
+		img = processImage(img, opts)
+	}
+
 	// Create a new 8-bit RGBA image
 	bounds := img.Bounds()
 	rgba := image.NewRGBA(bounds)
 
 	// Draw the original image onto the new RGBA image
 	draw.Draw(rgba, bounds, img, bounds.Min, draw.Src)
+	applyInk(rgba, opts.ink)
 
 	// Always encode as JPEG for better gopdf compatibility (avoids PNG parser issues)
 	var buf bytes.Buffer
@@ -517,103 +750,3 @@ func convertTo8Bit(imageData []byte) ([]byte, error) {
 
 	return buf.Bytes(), nil
 }
-
-func GeneratePDF(cards []Card) (*bytes.Buffer, error) {
-	pageW, pageH := 197.0, 269.0
-	var buf bytes.Buffer
-	pdf := gopdf.GoPdf{}
-	pdf.Start(gopdf.Config{PageSize: gopdf.Rect{W: pageW, H: pageH}})
-
-	var allURIs []string
-	var cardNames []string
-	for _, card := range cards {
-		for q := 0; q < card.Quantity; q++ {
-			for _, imageURI := range card.ImageURIs {
-				allURIs = append(allURIs, imageURI)
-				cardNames = append(cardNames, card.Name)
-			}
-		}
-	}
-
-	if len(allURIs) == 0 {
-		_, err := pdf.WriteTo(&buf)
-		if err != nil {
-			log.Print(err.Error())
-			return nil, err
-		}
-		return &buf, nil
-	}
-
-	imageData := make([][]byte, len(allURIs))
-	errs := make([]error, len(allURIs))
-
-	var wg sync.WaitGroup
-	wg.Add(len(allURIs))
-	for i, uri := range allURIs {
-		go func(i int, uri string) {
-			defer wg.Done()
-
-			log.Printf("Fetching image for %s: %s", cardNames[i], uri)
-			body, err := FetchImageWithRetry(uri, 2)
-			if err != nil {
-				log.Printf("Failed to fetch image for %s: %v", cardNames[i], err)
-				errs[i] = err
-				return
-			}
-			log.Printf("Successfully fetched image for %s (%d bytes)", cardNames[i], len(body))
-			imageData[i] = body
-		}(i, uri)
-	}
-	wg.Wait()
-
-	var failedImages []int
-	for i, err := range errs {
-		if err != nil {
-			log.Printf("Failed to fetch image %d (%s) for card %s: %v", i+1, allURIs[i], cardNames[i], err)
-			failedImages = append(failedImages, i)
-		}
-	}
-
-	if len(failedImages) > 0 {
-		log.Printf("Warning: Failed to fetch %d out of %d images. Continuing with available images.", len(failedImages), len(allURIs))
-	}
-
-	for i := range allURIs {
-		// Skip failed images
-		if errs[i] != nil {
-			log.Printf("Skipping page for %s due to failed image fetch", cardNames[i])
-			continue
-		}
-
-		log.Printf("Adding page for %s", cardNames[i])
-
-		pdf.AddPage()
-
-		pdf.SetFillColor(0, 0, 0)
-		pdf.Rectangle(0, 0, pageW, pageH, "F", 0, 0)
-
-		// Convert image to 8-bit if necessary
-		convertedImageData, err := convertTo8Bit(imageData[i])
-		if err != nil {
-			log.Printf("Failed to convert image for %s: %v", cardNames[i], err)
-			continue // Skip this image instead of failing the entire PDF
-		}
-
-		imgHolder, err := gopdf.ImageHolderByReader(bytes.NewReader(convertedImageData))
-		if err != nil {
-			log.Printf("Failed to create image holder for %s: %v", cardNames[i], err)
-			continue // Skip this image instead of failing the entire PDF
-		}
-
-		x, y := (pageW-180)/2, (pageH-252)/2
-		pdf.ImageByHolder(imgHolder, x, y, &gopdf.Rect{W: 180, H: 252})
-		log.Printf("Finished page for %s", cardNames[i])
-	}
-
-	_, err := pdf.WriteTo(&buf)
-	if err != nil {
-		log.Print(err.Error())
-		return nil, err
-	}
-	return &buf, nil
-}
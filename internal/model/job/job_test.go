@@ -0,0 +1,54 @@
+package job
+
+import (
+	"context"
+	"testing"
+)
+
+// taskMessage delivers a DecklistTask to ProcessDecklistHandler the way the queue does
+type taskMessage struct {
+	*DecklistTask
+}
+
+func (m taskMessage) Payload() []byte {
+	return m.Bytes()
+}
+
+// runTask processes decklist as a job with options and returns the finished job
+func runTask(t *testing.T, decklist string, options JobOptions) *GrimoireJob {
+	t.Helper()
+	job := NewGrimoireJob()
+	jobs.Store(job.ID, job)
+	t.Cleanup(func() { jobs.Delete(job.ID) })
+
+	task := &DecklistTask{JobID: job.ID, Decklist: decklist, Options: options}
+	ProcessDecklistHandler(context.Background(), taskMessage{task})
+	return job
+}
+
+func TestProcessDecklistNoValidCards(t *testing.T) {
+	useFakeScryfall(t)
+
+	tests := []struct {
+		name        string
+		decklist    string
+		diagnostics int
+	}{
+		{"every line invalid", "not a card\nnor this", 2},
+		{"every card unknown", "1 Black Lotus (lea) 232\n1 Mox Pearl (lea) 263", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := runTask(t, tt.decklist, JobOptions{SkipInvalid: true})
+			if job.Status != "error" || job.Error == nil {
+				t.Errorf("status = %s (%v), want error", job.Status, job.Error)
+			}
+			if job.PDF != nil {
+				t.Error("job has a PDF")
+			}
+			if len(job.Diagnostics) != tt.diagnostics {
+				t.Errorf("got %d diagnostics, want %d: %+v", len(job.Diagnostics), tt.diagnostics, job.Diagnostics)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package job
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CropMarks selects how cut guides are drawn around the cards
+type CropMarks string
+
+const (
+	CropMarksNone    CropMarks = "none"    // no cut guides
+	CropMarksLines   CropMarks = "lines"   // guides in the page margins along every card edge
+	CropMarksCorners CropMarks = "corners" // short marks at every card corner, outside the bleed
+)
+
+// ParseCropMarks validates a crop mark style from the submit API
+func ParseCropMarks(style string) (CropMarks, error) {
+	switch marks := CropMarks(strings.ToLower(strings.TrimSpace(style))); marks {
+	case CropMarksNone, CropMarksLines, CropMarksCorners:
+		return marks, nil
+	}
+	return "", fmt.Errorf("unknown crop mark style %q", style)
+}
+
+// Layout describes how card images are arranged on PDF pages.
+// All sizes are in points.
+type Layout struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	PageW       float64   `json:"page_width"`
+	PageH       float64   `json:"page_height"`
+	CardW       float64   `json:"card_width"`  // trimmed card size
+	CardH       float64   `json:"card_height"` // trimmed card size
+	Bleed       float64   `json:"bleed"`       // printed area around each card that is cut away
+	Margin      float64   `json:"margin"`      // minimum space between the page edge and a card's trim edge
+	Gutter      float64   `json:"gutter"`      // space between the trim edges of neighbouring cards
+	CropMarks   CropMarks `json:"crop_marks"`
+	// Registration adds print-and-cut registration marks (Silhouette/Cricut) to the page corners
+	Registration bool `json:"registration"`
+	// Paper marks a fixed paper size. Paper layouts fit fewer cards to make room for
+	// marks, other layouts grow the page around the card instead.
+	Paper bool `json:"paper"`
+}
+
+const (
+	pointsPerInch = 72.0
+	pointsPerMM   = pointsPerInch / 25.4
+)
+
+// Layout presets, keyed by the name used in the submit API
+var (
+	layoutPresets   = make(map[string]Layout)
+	layoutPresetsMu sync.RWMutex
+)
+
+func init() {
+	RegisterLayout(Layout{
+		Name:        "single",
+		Description: "One card per page with a black bleed margin",
+		PageW:       197, PageH: 269,
+		CardW: 180, CardH: 252,
+		Bleed: 8.5, Margin: 8.5,
+		CropMarks: CropMarksNone,
+	})
+	RegisterLayout(Layout{
+		Name:        "letter",
+		Description: "Real-size cards tiled 3x3 on US Letter paper for home printing",
+		PageW:       8.5 * pointsPerInch, PageH: 11 * pointsPerInch,
+		CardW: 180, CardH: 252,
+		Margin:    9,
+		CropMarks: CropMarksNone,
+		Paper:     true,
+	})
+	RegisterLayout(Layout{
+		Name:        "a4",
+		Description: "Real-size cards tiled 3x3 on A4 paper for home printing",
+		PageW:       210 * pointsPerMM, PageH: 297 * pointsPerMM,
+		CardW: 180, CardH: 252,
+		Margin:    9,
+		CropMarks: CropMarksNone,
+		Paper:     true,
+	})
+	RegisterLayout(Layout{
+		Name:        "mpc",
+		Description: "MakePlayingCards poker size, 2.74\" x 3.74\" with 1/8\" bleed",
+		PageW:       2.74 * pointsPerInch, PageH: 3.74 * pointsPerInch,
+		CardW: 2.5 * pointsPerInch, CardH: 3.5 * pointsPerInch,
+		Bleed: 0.12 * pointsPerInch, Margin: 0.12 * pointsPerInch,
+		CropMarks: CropMarksNone,
+	})
+	RegisterLayout(Layout{
+		Name:        "printerstudio",
+		Description: "PrinterStudio 63 x 88 mm cards with 3 mm bleed",
+		PageW:       69 * pointsPerMM, PageH: 94 * pointsPerMM,
+		CardW: 63 * pointsPerMM, CardH: 88 * pointsPerMM,
+		Bleed: 3 * pointsPerMM, Margin: 3 * pointsPerMM,
+		CropMarks: CropMarksNone,
+	})
+}
+
+// RegisterLayout adds or replaces a named layout preset
+func RegisterLayout(layout Layout) {
+	layoutPresetsMu.Lock()
+	defer layoutPresetsMu.Unlock()
+	layoutPresets[strings.ToLower(layout.Name)] = layout
+}
+
+// LayoutByName returns the named layout preset.
+// An empty name selects the "single" preset.
+func LayoutByName(name string) (Layout, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = "single"
+	}
+
+	layoutPresetsMu.RLock()
+	defer layoutPresetsMu.RUnlock()
+	layout, ok := layoutPresets[name]
+	if !ok {
+		return Layout{}, fmt.Errorf("unknown layout %q", name)
+	}
+	return layout, nil
+}
+
+// LayoutPresets returns all registered layout presets sorted by name
+func LayoutPresets() []Layout {
+	layoutPresetsMu.RLock()
+	defer layoutPresetsMu.RUnlock()
+
+	presets := make([]Layout, 0, len(layoutPresets))
+	for _, layout := range layoutPresets {
+		presets = append(presets, layout)
+	}
+	sort.Slice(presets, func(i, j int) bool { return presets[i].Name < presets[j].Name })
+	return presets
+}
+
+// ResolveLayout returns the job's layout preset with any per-job overrides applied
+func (o JobOptions) ResolveLayout() (Layout, error) {
+	layout, err := LayoutByName(o.Layout)
+	if err != nil {
+		return Layout{}, err
+	}
+
+	if o.Gutter != nil {
+		layout.Gutter = *o.Gutter
+	}
+	if o.BleedSize != nil {
+		layout.Bleed = *o.BleedSize
+	}
+	if o.CutLines != nil {
+		layout.CropMarks = CropMarksNone
+		if *o.CutLines {
+			layout.CropMarks = CropMarksLines
+		}
+	}
+	if o.CropMarks != "" {
+		if layout.CropMarks, err = ParseCropMarks(o.CropMarks); err != nil {
+			return Layout{}, err
+		}
+	}
+	if o.Registration {
+		layout.Registration = true
+	}
+	layout = layout.withMarkSpace()
+
+	if err := layout.Validate(); err != nil {
+		return Layout{}, err
+	}
+	return layout, nil
+}
+
+// Validate checks that at least one card fits on a page and that bleeds don't overlap
+func (l Layout) Validate() error {
+	switch {
+	case l.Gutter < 0 || l.Bleed < 0 || l.Margin < 0:
+		return fmt.Errorf("layout %q: sizes must not be negative", l.Name)
+	case l.Bleed > l.Margin:
+		return fmt.Errorf("layout %q: bleed %.1fpt does not fit in the %.1fpt margin", l.Name, l.Bleed, l.Margin)
+	case l.CardsPerPage() == 0:
+		return fmt.Errorf("layout %q: no cards fit on a %.0fx%.0fpt page", l.Name, l.PageW, l.PageH)
+	case l.CardsPerPage() > 1 && l.Gutter < 2*l.Bleed:
+		return fmt.Errorf("layout %q: a %.1fpt gutter is too narrow for a %.1fpt bleed", l.Name, l.Gutter, l.Bleed)
+	}
+	return nil
+}
+
+// markSpace returns the margin needed to keep crop and registration marks outside the bleed
+func (l Layout) markSpace() float64 {
+	var space float64
+	if l.CropMarks == CropMarksCorners {
+		space = l.Bleed + cropMarkOffset + cropMarkLength
+	}
+	if l.Registration {
+		space = max(space, registrationInset+registrationSize+registrationClearance)
+	}
+	return space
+}
+
+// withMarkSpace widens the margin to fit the layout's marks, and on card-sized
+// pages the bleed too. Paper layouts give up card slots for the wider margin,
+// card-sized pages grow around the card.
+func (l Layout) withMarkSpace() Layout {
+	need := l.markSpace()
+	if !l.Paper {
+		need = max(need, l.Bleed)
+	}
+	if need <= l.Margin {
+		return l
+	}
+
+	if !l.Paper {
+		grow := need - l.Margin
+		l.PageW += 2 * grow
+		l.PageH += 2 * grow
+	}
+	l.Margin = need
+	return l
+}
+
+// Grid returns how many columns and rows of cards fit on a page
+func (l Layout) Grid() (cols, rows int) {
+	const epsilon = 1e-6 // absorb rounding in unit conversions
+	cols = int((l.PageW-2*l.Margin+l.Gutter)/(l.CardW+l.Gutter) + epsilon)
+	rows = int((l.PageH-2*l.Margin+l.Gutter)/(l.CardH+l.Gutter) + epsilon)
+	return max(cols, 0), max(rows, 0)
+}
+
+// CardsPerPage returns the number of card slots on each page
+func (l Layout) CardsPerPage() int {
+	cols, rows := l.Grid()
+	return cols * rows
+}
+
+// gridOrigin returns the top-left trim corner of the card grid, centered on the page
+func (l Layout) gridOrigin() (x, y float64) {
+	cols, rows := l.Grid()
+	gridW := float64(cols)*l.CardW + float64(cols-1)*l.Gutter
+	gridH := float64(rows)*l.CardH + float64(rows-1)*l.Gutter
+	return (l.PageW - gridW) / 2, (l.PageH - gridH) / 2
+}
+
+// Slot returns the top-left trim corner of the card at the given slot on a page.
+// Slots are numbered left to right, top to bottom.
+func (l Layout) Slot(slot int) (x, y float64) {
+	cols, _ := l.Grid()
+	x0, y0 := l.gridOrigin()
+	col, row := slot%cols, slot/cols
+	return x0 + float64(col)*(l.CardW+l.Gutter), y0 + float64(row)*(l.CardH+l.Gutter)
+}
+
+// MirrorSlot returns the slot on the back of a sheet that lines up with slot
+// when the sheet is flipped along its long edge
+func (l Layout) MirrorSlot(slot int) int {
+	cols, _ := l.Grid()
+	col, row := slot%cols, slot/cols
+	return row*cols + (cols - 1 - col)
+}
@@ -0,0 +1,123 @@
+package job
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestLayoutPresets(t *testing.T) {
+	type point struct{ x, y float64 }
+	tests := []struct {
+		layout       string
+		registration bool
+		pageW, pageH float64
+		slots        []point // top-left trim corner of each slot
+		mirror       []int   // MirrorSlot of each slot
+	}{
+		{
+			layout: "letter",
+			pageW:  612, pageH: 792,
+			slots: []point{
+				{36, 18}, {216, 18}, {396, 18},
+				{36, 270}, {216, 270}, {396, 270},
+				{36, 522}, {216, 522}, {396, 522},
+			},
+			mirror: []int{2, 1, 0, 5, 4, 3, 8, 7, 6},
+		},
+		{
+			layout: "letter", registration: true,
+			pageW: 612, pageH: 792,
+			slots:  []point{{126, 144}, {306, 144}, {126, 396}, {306, 396}},
+			mirror: []int{1, 0, 3, 2},
+		},
+		{
+			layout: "a4",
+			pageW:  595.28, pageH: 841.89,
+			slots: []point{
+				{27.64, 42.94}, {207.64, 42.94}, {387.64, 42.94},
+				{27.64, 294.94}, {207.64, 294.94}, {387.64, 294.94},
+				{27.64, 546.94}, {207.64, 546.94}, {387.64, 546.94},
+			},
+			mirror: []int{2, 1, 0, 5, 4, 3, 8, 7, 6},
+		},
+		{
+			layout: "a4", registration: true,
+			pageW: 595.28, pageH: 841.89,
+			slots:  []point{{117.64, 168.94}, {297.64, 168.94}, {117.64, 420.94}, {297.64, 420.94}},
+			mirror: []int{1, 0, 3, 2},
+		},
+		// Card-sized pages grow around the card to fit registration marks
+		{layout: "mpc", pageW: 197.28, pageH: 269.28, slots: []point{{8.64, 8.64}}, mirror: []int{0}},
+		{layout: "mpc", registration: true, pageW: 278, pageH: 350, slots: []point{{49, 49}}, mirror: []int{0}},
+		{layout: "printerstudio", pageW: 195.59, pageH: 266.46, slots: []point{{8.5, 8.5}}, mirror: []int{0}},
+		{layout: "printerstudio", registration: true, pageW: 276.58, pageH: 347.45, slots: []point{{49, 49}}, mirror: []int{0}},
+	}
+	near := func(a, b float64) bool { return math.Abs(a-b) < 0.01 }
+	for _, tt := range tests {
+		l, err := JobOptions{Layout: tt.layout, Registration: tt.registration}.ResolveLayout()
+		if err != nil {
+			t.Errorf("%s (registration %v): %v", tt.layout, tt.registration, err)
+			continue
+		}
+		if !near(l.PageW, tt.pageW) || !near(l.PageH, tt.pageH) {
+			t.Errorf("%s (registration %v): page %.2fx%.2f, want %.2fx%.2f", tt.layout, tt.registration, l.PageW, l.PageH, tt.pageW, tt.pageH)
+		}
+		if n := l.CardsPerPage(); n != len(tt.slots) {
+			t.Errorf("%s (registration %v): %d cards per page, want %d", tt.layout, tt.registration, n, len(tt.slots))
+			continue
+		}
+
+		var mirror []int
+		for slot, want := range tt.slots {
+			if x, y := l.Slot(slot); !near(x, want.x) || !near(y, want.y) {
+				t.Errorf("%s (registration %v): slot %d at %.2f,%.2f, want %.2f,%.2f", tt.layout, tt.registration, slot, x, y, want.x, want.y)
+			}
+			mirror = append(mirror, l.MirrorSlot(slot))
+
+			// The back of a card sits where the front lands once the sheet is flipped
+			x, y := l.Slot(slot)
+			backX, backY := l.Slot(l.MirrorSlot(slot))
+			if !near(backX, l.PageW-x-l.CardW) || !near(backY, y) {
+				t.Errorf("%s (registration %v): slot %d backed at %.2f,%.2f, want %.2f,%.2f", tt.layout, tt.registration, slot, backX, backY, l.PageW-x-l.CardW, y)
+			}
+		}
+		if !reflect.DeepEqual(mirror, tt.mirror) {
+			t.Errorf("%s (registration %v): mirror slots %v, want %v", tt.layout, tt.registration, mirror, tt.mirror)
+		}
+	}
+}
+
+func TestResolveLayoutBleed(t *testing.T) {
+	bleed := func(pt float64) *float64 { return &pt }
+	tests := []struct {
+		options      JobOptions
+		pageW, pageH float64 // zero when the layout is rejected
+		margin       float64
+	}{
+		{JobOptions{Layout: "single", BleedSize: bleed(8.5)}, 197, 269, 8.5},
+		{JobOptions{Layout: "single", BleedSize: bleed(18)}, 216, 288, 18},
+		{JobOptions{Layout: "mpc", BleedSize: bleed(18)}, 216, 288, 18},
+		// Registration marks need more room than the bleed
+		{JobOptions{Layout: "single", BleedSize: bleed(18), Registration: true}, 278, 350, 49},
+		{JobOptions{Layout: "letter", BleedSize: bleed(4), Gutter: bleed(8)}, 612, 792, 9},
+		{JobOptions{Layout: "letter", BleedSize: bleed(18), Gutter: bleed(36)}, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		l, err := tt.options.ResolveLayout()
+		if tt.pageW == 0 {
+			if err == nil {
+				t.Errorf("%s with a %gpt bleed: got a %.0fx%.0fpt page, want an error", tt.options.Layout, *tt.options.BleedSize, l.PageW, l.PageH)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s with a %gpt bleed: %v", tt.options.Layout, *tt.options.BleedSize, err)
+			continue
+		}
+		if math.Abs(l.PageW-tt.pageW) > 0.01 || math.Abs(l.PageH-tt.pageH) > 0.01 || l.Margin != tt.margin {
+			t.Errorf("%s with a %gpt bleed: %.2fx%.2fpt page with a %.2fpt margin, want %.2fx%.2fpt with %.2fpt", tt.options.Layout, *tt.options.BleedSize,
+				l.PageW, l.PageH, l.Margin, tt.pageW, tt.pageH, tt.margin)
+		}
+	}
+}
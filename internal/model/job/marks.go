@@ -0,0 +1,141 @@
+package job
+
+import "github.com/signintech/gopdf"
+
+// Crop and registration mark geometry, in points
+const (
+	cropMarkOffset = 3.0  // gap between the bleed edge and a crop mark
+	cropMarkLength = 12.0 // length of each crop mark
+	cropMarkWidth  = 0.5  // stroke width of crop marks
+
+	registrationInset     = 28.0 // distance of the marks from the page edges (~10mm)
+	registrationSize      = 14.0 // side of the filled square mark (~5mm)
+	registrationLength    = 56.0 // arm length of the corner brackets (~20mm)
+	registrationWidth     = 1.5  // stroke width of the corner brackets
+	registrationClearance = 7.0  // empty space between the marks and the cards
+)
+
+type markRect struct {
+	x0, y0, x1, y1 float64
+}
+
+func (r markRect) overlaps(o markRect) bool {
+	return r.x0 < o.x1 && o.x0 < r.x1 && r.y0 < o.y1 && o.y0 < r.y1
+}
+
+// drawCutLines draws thin guides in the page margins along every card edge,
+// so the sheet can be cut with a ruler or trimmer without marking the cards
+func (l Layout) drawCutLines(pdf *gopdf.GoPdf) {
+	gap := l.Bleed + 2 // keep guides clear of the printed area
+
+	cols, rows := l.Grid()
+	x0, y0 := l.gridOrigin()
+	x1 := x0 + float64(cols)*l.CardW + float64(cols-1)*l.Gutter
+	y1 := y0 + float64(rows)*l.CardH + float64(rows-1)*l.Gutter
+
+	pdf.SetLineWidth(0.25)
+	pdf.SetStrokeColor(128, 128, 128)
+
+	for col := 0; col < cols; col++ {
+		left := x0 + float64(col)*(l.CardW+l.Gutter)
+		for _, x := range []float64{left, left + l.CardW} {
+			if y0-gap > 0 {
+				pdf.Line(x, 0, x, y0-gap)
+				pdf.Line(x, y1+gap, x, l.PageH)
+			}
+		}
+	}
+
+	for row := 0; row < rows; row++ {
+		top := y0 + float64(row)*(l.CardH+l.Gutter)
+		for _, y := range []float64{top, top + l.CardH} {
+			if x0-gap > 0 {
+				pdf.Line(0, y, x0-gap, y)
+				pdf.Line(x1+gap, y, l.PageW, y)
+			}
+		}
+	}
+}
+
+// bleedBox returns the area printed for the card in slot, including its bleed
+func (l Layout) bleedBox(slot int) markRect {
+	x, y := l.Slot(slot)
+	return markRect{x - l.Bleed, y - l.Bleed, x + l.CardW + l.Bleed, y + l.CardH + l.Bleed}
+}
+
+// drawCornerMarks draws crop marks at the corners of the cards in slots. Marks start
+// outside the bleed, and marks that would run into a neighbouring card are left out.
+func (l Layout) drawCornerMarks(pdf *gopdf.GoPdf, slots []int) {
+	boxes := make([]markRect, 0, l.CardsPerPage())
+	for slot := 0; slot < l.CardsPerPage(); slot++ {
+		boxes = append(boxes, l.bleedBox(slot))
+	}
+	page := markRect{0, 0, l.PageW, l.PageH}
+	start := l.Bleed + cropMarkOffset
+	half := cropMarkWidth / 2
+
+	pdf.SetLineWidth(cropMarkWidth)
+	pdf.SetStrokeColor(0, 0, 0)
+
+	for _, slot := range slots {
+		x, y := l.Slot(slot)
+		for _, corner := range [4][4]float64{
+			// corner x, corner y, outward x direction, outward y direction
+			{x, y, -1, -1},
+			{x + l.CardW, y, 1, -1},
+			{x, y + l.CardH, -1, 1},
+			{x + l.CardW, y + l.CardH, 1, 1},
+		} {
+			cx, cy, dx, dy := corner[0], corner[1], corner[2], corner[3]
+
+			// Horizontal mark along the card's top or bottom edge, then vertical along its side
+			hx0, hx1 := cx+dx*start, cx+dx*(start+cropMarkLength)
+			vy0, vy1 := cy+dy*start, cy+dy*(start+cropMarkLength)
+			for _, mark := range []markRect{
+				{min(hx0, hx1), cy - half, max(hx0, hx1), cy + half},
+				{cx - half, min(vy0, vy1), cx + half, max(vy0, vy1)},
+			} {
+				if !l.markFits(mark, page, boxes) {
+					continue
+				}
+				if mark.x1-mark.x0 > mark.y1-mark.y0 {
+					pdf.Line(mark.x0, cy, mark.x1, cy)
+				} else {
+					pdf.Line(cx, mark.y0, cx, mark.y1)
+				}
+			}
+		}
+	}
+}
+
+// markFits reports whether a mark lies on the page without touching any card's bleed box
+func (l Layout) markFits(mark, page markRect, boxes []markRect) bool {
+	if mark.x0 < page.x0 || mark.y0 < page.y0 || mark.x1 > page.x1 || mark.y1 > page.y1 {
+		return false
+	}
+	for _, box := range boxes {
+		if mark.overlaps(box) {
+			return false
+		}
+	}
+	return true
+}
+
+// drawRegistrationMarks draws Silhouette-style print-and-cut registration marks:
+// a filled square at the top left and corner brackets at the top right and bottom left
+func (l Layout) drawRegistrationMarks(pdf *gopdf.GoPdf) {
+	right := l.PageW - registrationInset
+	bottom := l.PageH - registrationInset
+
+	pdf.SetFillColor(0, 0, 0)
+	pdf.Rectangle(registrationInset, registrationInset, registrationInset+registrationSize, registrationInset+registrationSize, "F", 0, 0)
+
+	pdf.SetLineWidth(registrationWidth)
+	pdf.SetStrokeColor(0, 0, 0)
+
+	pdf.Line(right-registrationLength, registrationInset, right, registrationInset)
+	pdf.Line(right, registrationInset, right, registrationInset+registrationLength)
+
+	pdf.Line(registrationInset, bottom-registrationLength, registrationInset, bottom)
+	pdf.Line(registrationInset, bottom, registrationInset+registrationLength, bottom)
+}
@@ -0,0 +1,129 @@
+package job
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// CardOrder selects the order cards are printed in
+type CardOrder string
+
+const (
+	OrderDecklist     CardOrder = "decklist"     // as listed in the decklist
+	OrderAlphabetical CardOrder = "alphabetical" // by card name
+	OrderColorType    CardOrder = "color_type"   // by colour, then card type, then name
+	OrderSection      CardOrder = "section"      // grouped by decklist section, in decklist order
+)
+
+// ParseCardOrder validates a card order from the submit API. An empty order selects OrderDecklist.
+func ParseCardOrder(order string) (CardOrder, error) {
+	switch o := CardOrder(strings.ToLower(strings.TrimSpace(order))); o {
+	case "":
+		return OrderDecklist, nil
+	case OrderDecklist, OrderAlphabetical, OrderColorType, OrderSection:
+		return o, nil
+	}
+	return "", fmt.Errorf("unknown card order %q", order)
+}
+
+// SortCards sorts cards in place. The sort is stable, so cards that compare
+// equal keep their decklist order and identical input gives identical output.
+func SortCards(cards []Card, order CardOrder) {
+	switch order {
+	case OrderAlphabetical:
+		sort.SliceStable(cards, func(i, j int) bool {
+			return strings.ToLower(cards[i].Name) < strings.ToLower(cards[j].Name)
+		})
+	case OrderColorType:
+		sort.SliceStable(cards, func(i, j int) bool {
+			ci, cj := colorRank(cards[i]), colorRank(cards[j])
+			if ci != cj {
+				return ci < cj
+			}
+			ti, tj := typeRank(cards[i]), typeRank(cards[j])
+			if ti != tj {
+				return ti < tj
+			}
+			return strings.ToLower(cards[i].Name) < strings.ToLower(cards[j].Name)
+		})
+	case OrderSection:
+		// Sections are ordered by where they first appear in the decklist
+		first := make(map[string]int)
+		for i, card := range cards {
+			if _, ok := first[card.Section]; !ok {
+				first[card.Section] = i
+			}
+		}
+		sort.SliceStable(cards, func(i, j int) bool {
+			return first[cards[i].Section] < first[cards[j].Section]
+		})
+	}
+}
+
+// colorRank orders cards white, blue, black, red, green, multicolour, colourless, then lands
+func colorRank(card Card) int {
+	if strings.Contains(frontTypeLine(card), "Land") {
+		return 8
+	}
+
+	colors := card.Colors
+	if len(colors) == 0 && len(card.CardFaces) > 0 {
+		colors = card.CardFaces[0].Colors
+	}
+
+	switch len(colors) {
+	case 0:
+		return 7
+	case 1:
+		return strings.Index("WUBRG", colors[0]) + 1
+	}
+	return 6
+}
+
+// cardTypes lists card types in the order OrderColorType groups them
+var cardTypes = []string{"Creature", "Planeswalker", "Battle", "Instant", "Sorcery", "Artifact", "Enchantment", "Land"}
+
+// typeRank returns the position of the card's first matching type in cardTypes
+func typeRank(card Card) int {
+	typeLine := frontTypeLine(card)
+	for i, cardType := range cardTypes {
+		if strings.Contains(typeLine, cardType) {
+			return i
+		}
+	}
+	return len(cardTypes)
+}
+
+// frontTypeLine returns the type line of the card's front face
+func frontTypeLine(card Card) string {
+	if len(card.CardFaces) > 0 && card.CardFaces[0].TypeLine != "" {
+		return card.CardFaces[0].TypeLine
+	}
+	return card.TypeLine
+}
+
+// isSectionName reports whether a header without a trailing colon names a section:
+// one of the section aliases, or the "About" block of Arena exports
+func isSectionName(name string) bool {
+	_, ok := sectionAliases[name]
+	return ok || name == "about"
+}
+
+var sectionHeaderRe = regexp.MustCompile(`^(?://\s*)?([A-Za-z][A-Za-z ]*?)\s*(?:\(\d+\))?\s*(:)?$`)
+
+// sectionHeader reports whether line is a decklist section header such as
+// "Sideboard", "SIDEBOARD:" or "// Commander", and returns the section it starts
+func sectionHeader(line string) (string, bool) {
+	matches := sectionHeaderRe.FindStringSubmatch(strings.TrimSpace(line))
+	if matches == nil {
+		return "", false
+	}
+
+	name := strings.ToLower(matches[1])
+	if matches[2] == "" && !strings.HasPrefix(line, "//") && !isSectionName(name) {
+		return "", false
+	}
+	return canonicalSection(name), true
+}
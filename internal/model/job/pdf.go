@@ -0,0 +1,426 @@
+package job
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/signintech/gopdf"
+)
+
+// DefaultCardBackURL is the standard Magic card back printed by duplex jobs that
+// look cards up on the Scryfall API
+const DefaultCardBackURL = "https://backs.scryfall.io/large/0/a/0aeebaf5-8c7d-4636-9e82-8c27447861f7.jpg"
+
+// PrintOptions controls how GeneratePDF renders a job
+type PrintOptions struct {
+	Layout    Layout
+	Duplex    bool   // follow every page of fronts with a mirrored page of backs
+	BackURL   string // generic back for cards without a back face, defaults to the card source's BackURL
+	BackImage []byte // uploaded generic back, used instead of BackURL when set
+
+	Bleed         BleedMode // how the layout's bleed area is produced
+	SquareCorners bool      // fill in the rounded corners of card images
+	Ink           InkMode   // grayscale or low-ink toning of card images
+
+	Render RenderMode // draw card fronts from images or as text proxies
+	Strict bool       // fail instead of printing text proxies for missing images
+}
+
+// ResolvePrintOptions validates the job options and returns the settings used by GeneratePDF
+func (o JobOptions) ResolvePrintOptions() (PrintOptions, error) {
+	layout, err := o.ResolveLayout()
+	if err != nil {
+		return PrintOptions{}, err
+	}
+
+	bleed, err := ParseBleedMode(o.Bleed)
+	if err != nil {
+		return PrintOptions{}, err
+	}
+
+	ink, err := ParseInkMode(o.Ink)
+	if err != nil {
+		return PrintOptions{}, err
+	}
+
+	render, err := ParseRenderMode(o.Render)
+	if err != nil {
+		return PrintOptions{}, err
+	}
+
+	opts := PrintOptions{
+		Layout:        layout,
+		Duplex:        o.Duplex,
+		BackURL:       o.BackURL,
+		Bleed:         bleed,
+		SquareCorners: o.SquareCorners,
+		Ink:           ink,
+		Render:        render,
+		Strict:        o.Strict,
+	}
+	if o.BackID != "" {
+		if opts.BackImage, err = LoadCardBack(o.BackID); err != nil {
+			return PrintOptions{}, err
+		}
+	}
+	return opts, nil
+}
+
+// validateBackURL checks that a generic back URL is downloaded from one of the card
+// source's image hosts, so jobs can't make the server fetch arbitrary addresses
+func validateBackURL(backURL string) error {
+	if backURL == "" {
+		return nil
+	}
+	u, err := url.Parse(backURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("invalid back_url %q", backURL)
+	}
+	hosts := getCardSource().ImageHosts()
+	if !slices.Contains(hosts, u.Host) {
+		return fmt.Errorf("back_url must be an image on %s; upload other backs as card_back", strings.Join(hosts, ", "))
+	}
+	return nil
+}
+
+// Warning describes a card image that could not be printed
+type Warning struct {
+	Card            string `json:"card"`
+	Set             string `json:"set"`
+	CollectorNumber string `json:"collector_number"`
+	Face            string `json:"face"`
+	Reason          string `json:"reason"`
+}
+
+// PDFResult is the output of GeneratePDF
+type PDFResult struct {
+	PDF        *bytes.Buffer
+	Warnings   []Warning       // card images replaced by text proxies
+	ImageCache ImageCacheStats // card images found in and missing from the image cache
+}
+
+// impression is one physical card in the output
+type impression struct {
+	card  *Card
+	face  string // face printed on the front, "front" or "back"
+	front string // image URI printed on the front
+	back  string // image URI printed on the back in duplex mode, empty for the generic back
+}
+
+// GeneratePDF fetches the images for cards and arranges them on pages according to opts.
+// Cards whose image fails to load are printed as text proxies with a warning, or fail the PDF
+// in strict mode, in which case the returned result carries the warnings.
+func GeneratePDF(cards []Card, opts PrintOptions) (*PDFResult, error) {
+	layout := opts.Layout
+	if opts.BackURL == "" && len(opts.BackImage) == 0 {
+		opts.BackURL = getCardSource().BackURL()
+	}
+
+	var buf bytes.Buffer
+	pdf := gopdf.GoPdf{}
+	pdf.Start(gopdf.Config{PageSize: gopdf.Rect{W: layout.PageW, H: layout.PageH}})
+
+	// In duplex mode a double-faced card's back face is printed behind its front,
+	// otherwise every face gets its own card
+	var impressions []impression
+	for i := range cards {
+		card := &cards[i]
+		for q := 0; q < card.Quantity; q++ {
+			if opts.Duplex {
+				impressions = append(impressions, impression{card: card, face: "front", front: card.ImageURIs["front"], back: card.ImageURIs["back"]})
+				continue
+			}
+			for _, face := range []string{"front", "back"} {
+				if uri, ok := card.ImageURIs[face]; ok {
+					impressions = append(impressions, impression{card: card, face: face, front: uri})
+				}
+			}
+		}
+	}
+
+	if len(impressions) == 0 {
+		_, err := pdf.WriteTo(&buf)
+		if err != nil {
+			log.Print(err.Error())
+			return nil, err
+		}
+		return &PDFResult{PDF: &buf}, nil
+	}
+
+	// Fetch every distinct image once, no matter how many cards use it.
+	// Text proxies only need the generic back.
+	imageNames := make(map[string]string)
+	var uris []string
+	addURI := func(uri, name string) {
+		if _, ok := imageNames[uri]; uri == "" || ok {
+			return
+		}
+		imageNames[uri] = name
+		uris = append(uris, uri)
+	}
+	for _, imp := range impressions {
+		if opts.Render == RenderText {
+			break
+		}
+		addURI(imp.front, imp.card.Name)
+		if opts.Duplex {
+			addURI(imp.back, imp.card.Name+" (back)")
+		}
+	}
+	if opts.Duplex {
+		addURI(opts.BackURL, "card back")
+	}
+
+	imgOpts := newImageOptions(opts)
+	holders, failures, cacheStats := fetchImageHolders(uris, imageNames, imgOpts)
+
+	var genericBack gopdf.ImageHolder
+	if opts.Duplex {
+		var err error
+		if genericBack, err = loadGenericBack(opts, holders, imgOpts); err != nil {
+			return nil, err
+		}
+	}
+
+	warnings := imageWarnings(impressions, failures)
+	if len(warnings) > 0 && opts.Strict {
+		return &PDFResult{Warnings: warnings, ImageCache: cacheStats}, fmt.Errorf("%d card images failed to load", len(warnings))
+	}
+	if len(warnings) > 0 || opts.Render == RenderText {
+		if err := addTextFont(&pdf); err != nil {
+			return nil, err
+		}
+	}
+
+	perPage := layout.CardsPerPage()
+	for start := 0; start < len(impressions); start += perPage {
+		page := impressions[start:min(start+perPage, len(impressions))]
+
+		log.Printf("Adding page %d", start/perPage+1)
+		layout.startPage(&pdf)
+		slots := make([]int, len(page))
+		for slot, imp := range page {
+			slots[slot] = slot
+			if img, ok := holders[imp.front]; ok {
+				layout.drawCard(&pdf, slot, img, opts.Bleed)
+				log.Printf("Placed %s", imp.card.Name)
+			} else {
+				layout.drawTextCard(&pdf, slot, imp.card, imp.face)
+				log.Printf("Placed text proxy for %s", imp.card.Name)
+			}
+		}
+		layout.finishPage(&pdf, slots, true)
+
+		if !opts.Duplex {
+			continue
+		}
+
+		// Backs are mirrored so each one lands behind its front when the sheet is flipped
+		layout.startPage(&pdf)
+		for slot, imp := range page {
+			slots[slot] = layout.MirrorSlot(slot)
+			switch img, ok := holders[imp.back]; {
+			case ok:
+				layout.drawCard(&pdf, slots[slot], img, opts.Bleed)
+			case imp.back != "":
+				layout.drawTextCard(&pdf, slots[slot], imp.card, "back")
+			default:
+				layout.drawCard(&pdf, slots[slot], genericBack, opts.Bleed)
+			}
+		}
+		layout.finishPage(&pdf, slots, false)
+	}
+
+	_, err := pdf.WriteTo(&buf)
+	if err != nil {
+		log.Print(err.Error())
+		return nil, err
+	}
+	return &PDFResult{PDF: &buf, Warnings: warnings, ImageCache: cacheStats}, nil
+}
+
+// imageWarnings returns one warning for every card image in failures, in print order
+func imageWarnings(impressions []impression, failures map[string]error) []Warning {
+	var warnings []Warning
+	reported := make(map[string]bool)
+	report := func(uri string, card *Card, face string) {
+		err, failed := failures[uri]
+		if !failed || reported[uri] {
+			return
+		}
+		reported[uri] = true
+		warnings = append(warnings, Warning{
+			Card:            card.Name,
+			Set:             card.Set,
+			CollectorNumber: card.CollectorNumber,
+			Face:            face,
+			Reason:          err.Error(),
+		})
+	}
+
+	for _, imp := range impressions {
+		report(imp.front, imp.card, imp.face)
+		report(imp.back, imp.card, "back")
+	}
+	return warnings
+}
+
+// fetchImageHolders concurrently fetches and converts the images at uris, using
+// the image cache where it can. Images that fail to load are logged and returned
+// with the reason in failures.
+func fetchImageHolders(uris []string, names map[string]string, imgOpts imageOptions) (map[string]gopdf.ImageHolder, map[string]error, ImageCacheStats) {
+	cache := getImageCache()
+	imageData := make([][]byte, len(uris))
+	cached := make([]bool, len(uris))
+	errs := make([]error, len(uris))
+
+	var wg sync.WaitGroup
+	wg.Add(len(uris))
+	for i, uri := range uris {
+		go func(i int, uri string) {
+			defer wg.Done()
+
+			if data, ok := cache.get(imageCacheKey(uri, imgOpts)); ok {
+				log.Printf("Using cached image for %s", names[uri])
+				imageData[i], cached[i] = data, true
+				return
+			}
+
+			log.Printf("Fetching image for %s: %s", names[uri], uri)
+			body, err := FetchImageWithRetry(uri, 2)
+			if err != nil {
+				log.Printf("Failed to fetch image for %s: %v", names[uri], err)
+				errs[i] = err
+				return
+			}
+			log.Printf("Successfully fetched image for %s (%d bytes)", names[uri], len(body))
+			imageData[i] = body
+		}(i, uri)
+	}
+	wg.Wait()
+
+	var stats ImageCacheStats
+	holders := make(map[string]gopdf.ImageHolder, len(uris))
+	failures := make(map[string]error)
+	for i, uri := range uris {
+		if cached[i] {
+			stats.Hits++
+		} else {
+			stats.Misses++
+		}
+		if errs[i] != nil {
+			failures[uri] = errs[i]
+			continue
+		}
+
+		// Cached images are already converted
+		data := imageData[i]
+		if !cached[i] {
+			converted, err := convertTo8Bit(data, imgOpts)
+			if err != nil {
+				log.Printf("Failed to prepare image for %s: %v", names[uri], err)
+				failures[uri] = err
+				continue
+			}
+			data = converted
+			cache.put(imageCacheKey(uri, imgOpts), data)
+		}
+
+		imgHolder, err := gopdf.ImageHolderByReader(bytes.NewReader(data))
+		if err != nil {
+			log.Printf("Failed to prepare image for %s: %v", names[uri], err)
+			failures[uri] = fmt.Errorf("failed to create image holder: %w", err)
+			continue
+		}
+
+		holders[uri] = imgHolder
+	}
+
+	if len(failures) > 0 {
+		log.Printf("Warning: Failed to load %d out of %d images", len(failures), len(uris))
+	}
+	if cache != nil {
+		log.Printf("Image cache: %d hits, %d misses", stats.Hits, stats.Misses)
+	}
+
+	return holders, failures, stats
+}
+
+// newImageHolder converts an image to 8-bit and wraps it for embedding in the PDF
+func newImageHolder(imageData []byte, imgOpts imageOptions) (gopdf.ImageHolder, error) {
+	convertedImageData, err := convertTo8Bit(imageData, imgOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	imgHolder, err := gopdf.ImageHolderByReader(bytes.NewReader(convertedImageData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create image holder: %w", err)
+	}
+	return imgHolder, nil
+}
+
+// loadGenericBack returns the back printed behind cards without a back face.
+// Unlike card images, a back that fails to load fails the whole PDF.
+func loadGenericBack(opts PrintOptions, holders map[string]gopdf.ImageHolder, imgOpts imageOptions) (gopdf.ImageHolder, error) {
+	if len(opts.BackImage) > 0 {
+		back, err := newImageHolder(opts.BackImage, imgOpts)
+		if err != nil {
+			return nil, fmt.Errorf("invalid card back image: %w", err)
+		}
+		return back, nil
+	}
+
+	back, ok := holders[opts.BackURL]
+	if !ok {
+		return nil, fmt.Errorf("failed to load card back image %s", opts.BackURL)
+	}
+	return back, nil
+}
+
+// startPage adds a new page and draws the cut lines, which sit behind the cards
+func (l Layout) startPage(pdf *gopdf.GoPdf) {
+	pdf.AddPage()
+
+	if l.CropMarks == CropMarksLines {
+		l.drawCutLines(pdf)
+	}
+}
+
+// finishPage draws the marks that surround the cards in slots. Registration marks
+// are only needed on the fronts, which the cutter reads.
+func (l Layout) finishPage(pdf *gopdf.GoPdf, slots []int, front bool) {
+	if l.CropMarks == CropMarksCorners {
+		l.drawCornerMarks(pdf, slots)
+	}
+	if l.Registration && front {
+		l.drawRegistrationMarks(pdf)
+	}
+}
+
+// drawCard places a card image in a slot. Images with a generated bleed cover the
+// whole bleed box, otherwise the bleed is filled black (or white) and the image covers the trim box.
+func (l Layout) drawCard(pdf *gopdf.GoPdf, slot int, img gopdf.ImageHolder, bleed BleedMode) {
+	x, y := l.Slot(slot)
+
+	if l.Bleed > 0 && bleed.generated() {
+		pdf.ImageByHolder(img, x-l.Bleed, y-l.Bleed, &gopdf.Rect{W: l.CardW + 2*l.Bleed, H: l.CardH + 2*l.Bleed})
+		return
+	}
+
+	if l.Bleed > 0 {
+		if bleed == BleedWhite {
+			pdf.SetFillColor(255, 255, 255)
+		} else {
+			pdf.SetFillColor(0, 0, 0)
+		}
+		pdf.Rectangle(x-l.Bleed, y-l.Bleed, x+l.CardW+l.Bleed, y+l.CardH+l.Bleed, "F", 0, 0)
+	}
+
+	pdf.ImageByHolder(img, x, y, &gopdf.Rect{W: l.CardW, H: l.CardH})
+}
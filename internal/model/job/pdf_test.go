@@ -0,0 +1,93 @@
+package job
+
+import (
+	"bytes"
+	"testing"
+)
+
+// fakeDeck looks a decklist up in the fake Scryfall API
+func fakeDeck(t *testing.T, decklist string) []Card {
+	t.Helper()
+	entries, errs := archidektParser{}.Parse(decklist, ParseOptions{})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	cards, _, diagnostics := lookupCards("Test", entries, PrintingPreference{})
+	if len(diagnostics) > 0 {
+		t.Fatalf("lookup failed: %+v", diagnostics)
+	}
+	return cards
+}
+
+func TestGeneratePDFDuplexOffline(t *testing.T) {
+	srv, requests := useFakeScryfall(t)
+	cards := fakeDeck(t, "2 Sol Ring (cmr) 472\n1 Delver of Secrets (isd) 51")
+
+	opts, err := JobOptions{Duplex: true}.ResolvePrintOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := GeneratePDF(cards, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Warnings) > 0 {
+		t.Errorf("warnings: %+v", result.Warnings)
+	}
+	if !bytes.HasPrefix(result.PDF.Bytes(), []byte("%PDF-")) {
+		t.Error("output is not a PDF")
+	}
+	if n := requests.count("GET " + cardBackPath); n != 1 {
+		t.Errorf("fetched the card back from %s %d times, want 1", srv.URL, n)
+	}
+}
+
+func TestGeneratePDFImageCache(t *testing.T) {
+	useFakeScryfall(t)
+	if err := InitImageCache(t.TempDir(), 1<<30); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { InitImageCache("", 0) })
+	cards := fakeDeck(t, "1 Sol Ring (cmr) 472\n1 Delver of Secrets (isd) 51\n1 Forest (iko) 274")
+
+	opts, err := JobOptions{}.ResolvePrintOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Delver of Secrets has two faces
+	for _, want := range []ImageCacheStats{{Misses: 4}, {Hits: 4}} {
+		result, err := GeneratePDF(cards, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.ImageCache != want {
+			t.Errorf("image cache = %+v, want %+v", result.ImageCache, want)
+		}
+	}
+}
+
+func TestValidateBackURL(t *testing.T) {
+	srv, _ := useFakeScryfall(t)
+
+	tests := []struct {
+		source  CardSource
+		backURL string
+		ok      bool
+	}{
+		{NewScryfall(DefaultScryfallURL, nil), "", true},
+		{NewScryfall(DefaultScryfallURL, nil), DefaultCardBackURL, true},
+		{NewScryfall(DefaultScryfallURL, nil), "https://cards.scryfall.io/large/back/0/0/0.jpg", true},
+		{NewScryfall(DefaultScryfallURL, nil), "https://example.com/back.png", false},
+		{NewScryfall(DefaultScryfallURL, nil), "http://169.254.169.254/latest/meta-data", false},
+		{NewScryfall(DefaultScryfallURL, nil), "file:///etc/passwd", false},
+		{getCardSource(), srv.URL + cardBackPath, true},
+		{getCardSource(), DefaultCardBackURL, false},
+		{getCardSource(), "http://localhost/back.png", false},
+	}
+	for _, tt := range tests {
+		SetCardSource(tt.source)
+		if err := validateBackURL(tt.backURL); (err == nil) != tt.ok {
+			t.Errorf("validateBackURL(%q) with hosts %v = %v, want ok %v", tt.backURL, tt.source.ImageHosts(), err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package job
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// PrintingMode selects which printing a card named without a collector number resolves to
+type PrintingMode string
+
+const (
+	PrintingDefault   PrintingMode = "default"   // Scryfall's choice for the name
+	PrintingNewest    PrintingMode = "newest"    // most recently released paper printing
+	PrintingOldest    PrintingMode = "oldest"    // first paper printing
+	PrintingCheapest  PrintingMode = "cheapest"  // paper printing with the lowest USD price
+	PrintingPreferred PrintingMode = "preferred" // first match in a list of preferred sets
+)
+
+// PrintingPreference picks printings for cards named without a collector number
+type PrintingPreference struct {
+	Mode PrintingMode
+	Sets []string // preferred set codes in order, for PrintingPreferred
+}
+
+// ResolvePrintingPreference validates the job's printing options. Preferred sets
+// without a mode select PrintingPreferred.
+func (o JobOptions) ResolvePrintingPreference() (PrintingPreference, error) {
+	pref := PrintingPreference{Mode: PrintingMode(strings.ToLower(strings.TrimSpace(o.Printing)))}
+	for _, set := range o.PreferredSets {
+		if set = strings.ToLower(strings.TrimSpace(set)); set != "" {
+			pref.Sets = append(pref.Sets, set)
+		}
+	}
+
+	switch pref.Mode {
+	case "":
+		pref.Mode = PrintingDefault
+		if len(pref.Sets) > 0 {
+			pref.Mode = PrintingPreferred
+		}
+	case PrintingDefault, PrintingNewest, PrintingOldest, PrintingCheapest:
+	case PrintingPreferred:
+		if len(pref.Sets) == 0 {
+			return PrintingPreference{}, fmt.Errorf("printing %q needs preferred sets", pref.Mode)
+		}
+	default:
+		return PrintingPreference{}, fmt.Errorf("unknown printing preference %q", o.Printing)
+	}
+	return pref, nil
+}
+
+// preferPrinting replaces card with the printing chosen by pref, keeping the
+// decklist details. Cards are left as they are when no printing matches.
+func preferPrinting(card *Card, set string, pref PrintingPreference, source CardSource) error {
+	if pref.Mode == "" || pref.Mode == PrintingDefault || card.OracleID == "" {
+		return nil
+	}
+
+	query := PrintingQuery{OracleID: card.OracleID, Set: set, Order: "released"}
+	switch pref.Mode {
+	case PrintingOldest:
+		query.Ascending = true
+	case PrintingCheapest:
+		query.Order = "usd"
+		query.Ascending = true
+	case PrintingPreferred:
+		query.Sets = pref.Sets
+	}
+
+	printings, err := source.Printings(query)
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return nil // no paper printing matches
+		}
+		return fmt.Errorf("printing search failed: %w", err)
+	}
+
+	chosen := -1
+	if pref.Mode == PrintingPreferred {
+		rank := len(pref.Sets)
+		for i, c := range printings {
+			for r, s := range pref.Sets[:rank] {
+				if strings.EqualFold(c.Set, s) {
+					chosen, rank = i, r
+					break
+				}
+			}
+		}
+	} else if len(printings) > 0 {
+		chosen = 0
+	}
+	if chosen < 0 {
+		return nil
+	}
+
+	printing := printings[chosen]
+	printing.Quantity = card.Quantity
+	printing.Finish = card.Finish
+	printing.Section = card.Section
+	*card = printing
+	return nil
+}
@@ -0,0 +1,86 @@
+package job
+
+import (
+	"log"
+)
+
+// RelatedCard is an entry in a Scryfall card's all_parts list
+type RelatedCard struct {
+	ID        string `json:"id"`
+	Component string `json:"component"` // "token", "meld_part", "meld_result" or "combo_piece"
+	Name      string `json:"name"`
+	TypeLine  string `json:"type_line"`
+}
+
+// meldResult returns the card a meld piece melds into, reporting false when card
+// isn't a meld piece
+func meldResult(card *Card) (RelatedCard, bool) {
+	for _, part := range card.AllParts {
+		if part.Component == "meld_result" && part.ID != card.ID {
+			return part, true
+		}
+	}
+	return RelatedCard{}, false
+}
+
+// relatedCards looks up the tokens and emblems the cards create and the meld pieces
+// they need, one copy of each, in batches like the decklist. Objects already in
+// cards are left out, as are meld results, which are printed on the back of their
+// pieces. Lookups that fail are logged and skipped. label prefixes the log lines.
+func relatedCards(label string, cards []Card) []Card {
+	source := getCardSource()
+
+	have := make(map[string]bool)
+	for _, card := range cards {
+		have[card.ID] = true
+		have[card.OracleID] = true
+	}
+	delete(have, "")
+
+	var entries []DeckEntry
+	var parts []RelatedCard
+	var parents []string // name of the card each part was found on
+	for _, card := range cards {
+		for _, part := range card.AllParts {
+			if have[part.ID] || (part.Component != "token" && part.Component != "meld_part") {
+				continue
+			}
+			have[part.ID] = true
+
+			section := SectionTokens
+			if part.Component == "meld_part" {
+				section = card.Section
+			}
+			entries = append(entries, DeckEntry{Quantity: 1, Name: part.Name, ScryfallID: part.ID, Section: section})
+			parts = append(parts, part)
+			parents = append(parents, card.Name)
+		}
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	results := make([]lookupResult, len(entries))
+	for _, i := range collectCards(label, entries, results, source, PrintingPreference{}) {
+		card, err := FetchCard(entries[i], source, PrintingPreference{})
+		results[i] = lookupResult{card: card, err: err}
+	}
+
+	var related []Card
+	for i, res := range results {
+		part := parts[i]
+		if res.err != nil {
+			log.Printf("%s: Failed to look up %s %q for %q: %v", label, part.Component, part.Name, parents[i], res.err)
+			continue
+		}
+		// Different printings of the same token share an oracle ID
+		if res.card.OracleID != "" && have[res.card.OracleID] {
+			continue
+		}
+		have[res.card.OracleID] = true
+
+		log.Printf("%s: Adding %s %q for %q", label, part.Component, res.card.Name, parents[i])
+		related = append(related, res.card)
+	}
+	return related
+}
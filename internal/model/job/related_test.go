@@ -0,0 +1,29 @@
+package job
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRelatedCards(t *testing.T) {
+	_, requests := useFakeScryfall(t)
+	cards := fakeDeck(t, "1 Bruna, the Fading Light (emn) 15a [Commander]\n1 Smothering Tithe (rna) 22")
+	before := requests.count("POST /cards/collection")
+
+	var got []string
+	for _, card := range relatedCards("Test", cards) {
+		got = append(got, card.Name+" ("+card.Section+")")
+	}
+	if want := []string{"Gisela, the Broken Blade (commander)", "Treasure (tokens)"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("related cards %v, want %v", got, want)
+	}
+	// Every part is looked up in one batch
+	if n := requests.count("POST /cards/collection") - before; n != 1 {
+		t.Errorf("made %d collection requests, want 1", n)
+	}
+	for _, id := range []string{"f5a8b1c6-9d7e-4f0a-2b3c-4e5f6a7b8c02", "a6b9c2d7-0e8f-4a1b-3c4d-5f6a7b8c9d02"} {
+		if n := requests.count("GET /cards/" + id); n != 0 {
+			t.Errorf("looked up %s on its own %d times", id, n)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package job
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultScryfallURL is the base URL of the Scryfall API
+const DefaultScryfallURL = "https://api.scryfall.com"
+
+// Scryfall is a CardSource backed by the Scryfall API, or by anything serving the
+// same endpoints, such as the fakescryfall package
+type Scryfall struct {
+	BaseURL  string // without a trailing slash
+	Client   *http.Client
+	CardBack string // generic card back image, defaults to DefaultCardBackURL
+}
+
+// cardBackPath is where APIs other than Scryfall's, such as the fakescryfall
+// package, serve the generic card back
+const cardBackPath = "/card-back.png"
+
+// NewScryfall returns a Scryfall source for the API at baseURL. A nil client gets
+// a client with a 30 second timeout. The generic card back comes from
+// backs.scryfall.io for the Scryfall API and from the API itself for any other.
+func NewScryfall(baseURL string, client *http.Client) *Scryfall {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	s := &Scryfall{BaseURL: strings.TrimRight(baseURL, "/"), Client: client}
+	if s.BaseURL != DefaultScryfallURL {
+		s.CardBack = s.BaseURL + cardBackPath
+	}
+	return s
+}
+
+// Card looks up a printing by Scryfall ID, MTGO catalog ID, set and collector
+// number (and language), or by fuzzy name within an optional set
+func (s *Scryfall) Card(entry DeckEntry) (Card, error) {
+	lookupURL := fmt.Sprintf("%s/cards/%s/%s", s.BaseURL, url.PathEscape(entry.Set), url.PathEscape(entry.CollectorNumber))
+	switch {
+	case entry.ScryfallID != "":
+		lookupURL = s.BaseURL + "/cards/" + url.PathEscape(entry.ScryfallID)
+	case entry.MTGOID != 0:
+		lookupURL = fmt.Sprintf("%s/cards/mtgo/%d", s.BaseURL, entry.MTGOID)
+	case entry.CollectorNumber == "":
+		query := url.Values{"fuzzy": {entry.Name}}
+		if entry.Set != "" {
+			query.Set("set", entry.Set)
+		}
+		lookupURL = s.BaseURL + "/cards/named?" + query.Encode()
+	case entry.Language != "" && entry.Language != "en":
+		lookupURL += "/" + url.PathEscape(entry.Language)
+	}
+
+	var card Card
+	if err := s.get(lookupURL, &card); err != nil {
+		return Card{}, err
+	}
+	return card, nil
+}
+
+// collectionResult is the response to a /cards/collection request
+type collectionResult struct {
+	Data     []Card           `json:"data"`
+	NotFound []CardIdentifier `json:"not_found"`
+}
+
+// Collection looks up to 75 cards with one /cards/collection request
+func (s *Scryfall) Collection(ids []CardIdentifier) ([]Card, []CardIdentifier, error) {
+	var result collectionResult
+	if err := s.post(s.BaseURL+"/cards/collection", map[string]any{"identifiers": ids}, &result); err != nil {
+		return nil, nil, err
+	}
+	return result.Data, result.NotFound, nil
+}
+
+// searchResult is a page of Scryfall card search results
+type searchResult struct {
+	Data []Card `json:"data"`
+}
+
+// Printings searches for the card's paper printings. Only the first page of
+// results is returned.
+func (s *Scryfall) Printings(query PrintingQuery) ([]Card, error) {
+	q := "oracleid:" + query.OracleID + " game:paper"
+	if query.Set != "" {
+		q += " set:" + query.Set
+	}
+	if len(query.Sets) > 0 {
+		sets := make([]string, len(query.Sets))
+		for i, set := range query.Sets {
+			sets[i] = "set:" + set
+		}
+		q += " (" + strings.Join(sets, " or ") + ")"
+	}
+
+	params := url.Values{"q": {q}, "unique": {"prints"}, "order": {query.Order}, "dir": {"desc"}}
+	if query.Ascending {
+		params.Set("dir", "asc")
+	}
+
+	var result searchResult
+	if err := s.get(s.BaseURL+"/cards/search?"+params.Encode(), &result); err != nil {
+		return nil, err
+	}
+	return result.Data, nil
+}
+
+// SetExists looks the set code up with /sets
+func (s *Scryfall) SetExists(code string) (bool, error) {
+	var result struct{}
+	err := s.get(s.BaseURL+"/sets/"+url.PathEscape(code), &result)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// SuggestName returns the card Scryfall's fuzzy search or autocomplete matches to name
+func (s *Scryfall) SuggestName(name string) (string, error) {
+	var card Card
+	if err := s.get(s.BaseURL+"/cards/named?fuzzy="+url.QueryEscape(name), &card); err == nil {
+		return card.Name, nil
+	}
+
+	var result struct {
+		Data []string `json:"data"`
+	}
+	if err := s.get(s.BaseURL+"/cards/autocomplete?q="+url.QueryEscape(name), &result); err != nil {
+		return "", err
+	}
+	if len(result.Data) == 0 {
+		return "", nil
+	}
+	return result.Data[0], nil
+}
+
+// BackURL returns the generic card back
+func (s *Scryfall) BackURL() string {
+	if s.CardBack == "" {
+		return DefaultCardBackURL
+	}
+	return s.CardBack
+}
+
+// scryfallImageHosts serve the images linked from the Scryfall API
+var scryfallImageHosts = []string{"cards.scryfall.io", "backs.scryfall.io"}
+
+// ImageHosts returns the API's host and the host of its card back, along with
+// Scryfall's image hosts for the Scryfall API
+func (s *Scryfall) ImageHosts() []string {
+	var hosts []string
+	for _, uri := range []string{s.BaseURL, s.BackURL()} {
+		if u, err := url.Parse(uri); err == nil && u.Host != "" && !slices.Contains(hosts, u.Host) {
+			hosts = append(hosts, u.Host)
+		}
+	}
+	if s.BaseURL == DefaultScryfallURL {
+		for _, host := range scryfallImageHosts {
+			if !slices.Contains(hosts, host) {
+				hosts = append(hosts, host)
+			}
+		}
+	}
+	return hosts
+}
+
+// ImageURIs points at the images of the card's printing
+func (s *Scryfall) ImageURIs(card *Card) map[string]string {
+	// Non-English printings have their own images
+	printing := fmt.Sprintf("%s/cards/%s/%s", s.BaseURL, url.PathEscape(card.Set), url.PathEscape(card.CollectorNumber))
+	if card.Lang != "" && card.Lang != "en" {
+		printing += "/" + url.PathEscape(card.Lang)
+	}
+
+	uris := map[string]string{
+		"front": printing + "?format=image&version=png",
+	}
+	switch card.Layout {
+	case "transform", "modal_dfc", "double_faced_token":
+		uris["back"] = printing + "?format=image&version=png&face=back"
+	case "meld":
+		// The back of a meld piece shows the card it melds into
+		if result, ok := meldResult(card); ok {
+			uris["back"] = s.BaseURL + "/cards/" + url.PathEscape(result.ID) + "?format=image&version=png"
+		}
+	}
+	return uris
+}
+
+// get fetches a Scryfall API URL and decodes the JSON response into v,
+// retrying network errors and rate limiting
+func (s *Scryfall) get(apiURL string, v any) error {
+	return s.do(http.MethodGet, apiURL, nil, v)
+}
+
+// post sends body as JSON to a Scryfall API URL and decodes the JSON
+// response into v, retrying network errors and rate limiting
+func (s *Scryfall) post(apiURL string, body any, v any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	return s.do(http.MethodPost, apiURL, data, v)
+}
+
+// do makes a Scryfall API request with an optional JSON body. GET responses are
+// answered from the card cache while they are fresh, and revalidated with a
+// conditional request once they expire. Other requests aren't cached: their
+// callers cache what they need.
+func (s *Scryfall) do(method, apiURL string, body []byte, v any) error {
+	var cache *cardCache
+	if method == http.MethodGet {
+		cache = getCardCache()
+	}
+	key := responseCacheKey(apiURL)
+	cached, ok := cache.load(key)
+	if ok && cache.fresh(cached) {
+		if err := json.Unmarshal(cached.Body, v); err == nil {
+			cache.count("hit")
+			return nil
+		}
+		ok = false // unusable, fetch it again
+	}
+
+	maxRetries := 3
+	baseDelay := 100 * time.Millisecond
+
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		rateLimitWait()
+
+		req, err := http.NewRequest(method, apiURL, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("invalid request: %w", err)
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if ok {
+			if cached.ETag != "" {
+				req.Header.Set("If-None-Match", cached.ETag)
+			}
+			if cached.LastModified != "" {
+				req.Header.Set("If-Modified-Since", cached.LastModified)
+			}
+		}
+
+		resp, err := s.Client.Do(req)
+		if err != nil {
+			if attempt == maxRetries-1 {
+				return fmt.Errorf("HTTP request failed after %d attempts: %w", maxRetries, err)
+			}
+			time.Sleep(baseDelay * time.Duration(1<<attempt))
+			continue
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			resp.Body.Close()
+			if attempt == maxRetries-1 {
+				return fmt.Errorf("API rate limited after %d attempts: %w", maxRetries, &APIError{StatusCode: resp.StatusCode})
+			}
+			delay := 5 * time.Second * time.Duration(1<<attempt)
+			log.Printf("Rate limited, waiting %v before retry %d/%d", delay, attempt+2, maxRetries+1)
+			time.Sleep(delay)
+			continue
+		}
+
+		// The cached response is still current
+		if resp.StatusCode == http.StatusNotModified && ok {
+			resp.Body.Close()
+			cached.FetchedAt = time.Now()
+			cache.store(key, cached)
+			cache.count("revalidated")
+			if err := json.Unmarshal(cached.Body, v); err != nil {
+				return fmt.Errorf("JSON decode failed: %w", err)
+			}
+			return nil
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			// Scryfall error objects explain what went wrong in "details"
+			var apiErr struct {
+				Details string `json:"details"`
+			}
+			json.NewDecoder(resp.Body).Decode(&apiErr)
+			resp.Body.Close()
+			return &APIError{StatusCode: resp.StatusCode, Details: apiErr.Details}
+		}
+
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			return fmt.Errorf("JSON decode failed: %w", err)
+		}
+
+		if cache != nil {
+			cache.store(key, &cardCacheEntry{
+				URL:          apiURL,
+				ETag:         resp.Header.Get("ETag"),
+				LastModified: resp.Header.Get("Last-Modified"),
+				FetchedAt:    time.Now(),
+				Body:         data,
+			})
+			if ok {
+				cache.count("refreshed")
+			} else {
+				cache.count("miss")
+			}
+		}
+
+		break
+	}
+	return nil
+}
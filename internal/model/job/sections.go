@@ -0,0 +1,205 @@
+package job
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Decklist sections. Section headers and categories naming any other section,
+// such as an Archidekt "Ramp" category, keep their own name and count as part of
+// the main deck.
+const (
+	SectionCommander  = "commander"
+	SectionCompanion  = "companion"
+	SectionMainboard  = "mainboard"
+	SectionSideboard  = "sideboard"
+	SectionMaybeboard = "maybeboard"
+	SectionTokens     = "tokens"
+)
+
+// sectionAliases maps the section names used by decklist exports to a section
+var sectionAliases = map[string]string{
+	"commander":   SectionCommander,
+	"commanders":  SectionCommander,
+	"companion":   SectionCompanion,
+	"deck":        SectionMainboard,
+	"main":        SectionMainboard,
+	"main deck":   SectionMainboard,
+	"mainboard":   SectionMainboard,
+	"side":        SectionSideboard,
+	"sideboard":   SectionSideboard,
+	"maybe":       SectionMaybeboard,
+	"maybeboard":  SectionMaybeboard,
+	"considering": SectionMaybeboard,
+	"token":       SectionTokens,
+	"tokens":      SectionTokens,
+}
+
+// canonicalSection returns the section a header or category name refers to. Names
+// that aren't a known section are returned in lower case.
+func canonicalSection(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if section, ok := sectionAliases[name]; ok {
+		return section
+	}
+	return name
+}
+
+// SectionFilter selects the decklist sections a job prints. Cards outside the known
+// sections, including lines with no section, belong to the main deck. A nil filter
+// prints every section except the maybeboard.
+type SectionFilter map[string]bool
+
+// sectionAll selects every section in a SectionFilter
+const sectionAll = "all"
+
+// ParseSections builds a SectionFilter from section names, where "all" selects every
+// section. No names gives the nil filter.
+func ParseSections(sections []string) SectionFilter {
+	var filter SectionFilter
+	for _, name := range sections {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		if filter == nil {
+			filter = SectionFilter{}
+		}
+		filter[canonicalSection(name)] = true
+	}
+	return filter
+}
+
+// Includes reports whether cards listed under section are printed
+func (f SectionFilter) Includes(section string) bool {
+	if section == "" {
+		section = SectionMainboard
+	}
+	if f == nil {
+		return section != SectionMaybeboard
+	}
+	if f[sectionAll] || f[section] {
+		return true
+	}
+	return !isKnownSection(section) && f[SectionMainboard]
+}
+
+// isKnownSection reports whether section is one of the Section constants
+func isKnownSection(section string) bool {
+	return sectionAliases[section] == section
+}
+
+// check returns an error naming the first section in the filter that is neither a
+// known section nor a category used by the parsed decklist
+func (f SectionFilter) check(entries []DeckEntry, parseErrors []error) error {
+	present := map[string]bool{}
+	for _, entry := range entries {
+		present[entry.Section] = true
+	}
+	for _, err := range parseErrors {
+		var lineErr *LineError
+		if errors.As(err, &lineErr) {
+			present[lineErr.Section] = true
+		}
+	}
+
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name != sectionAll && !isKnownSection(name) && !present[name] {
+			return fmt.Errorf("unknown section %q: use all, %s, %s, %s, %s, %s, %s or a category from the decklist", name,
+				SectionCommander, SectionCompanion, SectionMainboard, SectionSideboard, SectionMaybeboard, SectionTokens)
+		}
+	}
+	return nil
+}
+
+// filterSections returns the entries listed under sections the filter includes
+func filterSections(entries []DeckEntry, filter SectionFilter) []DeckEntry {
+	var included []DeckEntry
+	for _, entry := range entries {
+		if filter.Includes(entry.Section) {
+			included = append(included, entry)
+		}
+	}
+	return included
+}
+
+// filterParseErrors returns the parse errors for lines listed under sections the
+// filter includes, along with those that apply to the whole decklist
+func filterParseErrors(errs []error, filter SectionFilter) []error {
+	var included []error
+	for _, err := range errs {
+		var lineErr *LineError
+		if errors.As(err, &lineErr) && lineErr.Line > 0 && !filter.Includes(lineErr.Section) {
+			continue
+		}
+		included = append(included, err)
+	}
+	return included
+}
+
+var (
+	// archidektTagsRe matches the finish markers, category tags and label Archidekt can
+	// add to the end of a line, as in "1x Sol Ring (cmr) 263 *F* [Ramp] ^Have,#37d67a^"
+	archidektTagsRe     = regexp.MustCompile(`^(.*?)((?:\s+(?:\*[A-Za-z]+\*|\[[^\]]*\]|\^[^^]*\^))+)$`)
+	archidektFinishRe   = regexp.MustCompile(`\*[A-Za-z]+\*`)
+	archidektCategoryRe = regexp.MustCompile(`\[([^\]]*)\]`)
+	// archidektFlagRe matches the flags after a category name, as in "Maybeboard{noDeck}{noPrice}"
+	archidektFlagRe = regexp.MustCompile(`\{([^}]*)\}`)
+)
+
+// splitArchidektTags removes Archidekt's finish markers, categories and label from
+// line, returning what's left along with the finish and the section the categories
+// place the card in
+func splitArchidektTags(line string) (rest, finish, section string) {
+	matches := archidektTagsRe.FindStringSubmatch(line)
+	if matches == nil {
+		return line, "", ""
+	}
+
+	for _, marker := range archidektFinishRe.FindAllString(matches[2], -1) {
+		if f, ok := moxfieldFinishes[strings.ToUpper(marker)]; ok {
+			finish = f
+		}
+	}
+	var categories []string
+	for _, tag := range archidektCategoryRe.FindAllStringSubmatch(matches[2], -1) {
+		categories = append(categories, strings.Split(tag[1], ",")...)
+	}
+	return matches[1], finish, archidektSection(categories)
+}
+
+// archidektSection returns the section for a card's Archidekt categories: the first
+// category that names a known section, the maybeboard for categories kept out of the
+// deck, or else the first category
+func archidektSection(categories []string) string {
+	first, outOfDeck := "", false
+	for _, category := range categories {
+		name := strings.TrimSpace(archidektFlagRe.ReplaceAllString(category, ""))
+		if name == "" {
+			continue
+		}
+		section := canonicalSection(name)
+		if isKnownSection(section) {
+			return section
+		}
+		for _, flag := range archidektFlagRe.FindAllStringSubmatch(category, -1) {
+			if flag[1] == "noDeck" {
+				outOfDeck = true
+			}
+		}
+		if first == "" {
+			first = section
+		}
+	}
+	if outOfDeck {
+		return SectionMaybeboard
+	}
+	return first
+}
@@ -0,0 +1,81 @@
+package job
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSectionFilterCheck(t *testing.T) {
+	decklist := "1 Sol Ring (c21) 263 [Ramp]\n1 Opt (xln) 65\n\nSideboard\n1 Duress (m19) 94"
+	entries, parseErrors := archidektParser{}.Parse(decklist, ParseOptions{})
+
+	tests := []struct {
+		sections []string
+		valid    bool
+	}{
+		{nil, true},
+		{[]string{"all"}, true},
+		{[]string{"Commander", "main"}, true},
+		{[]string{"tokens"}, true},
+		{[]string{"ramp"}, true},
+		{[]string{"draw"}, false},
+		{[]string{"sideboard", "sidebaord"}, false},
+	}
+	for _, tt := range tests {
+		err := ParseSections(tt.sections).check(entries, parseErrors)
+		if (err == nil) != tt.valid {
+			t.Errorf("sections %v: got error %v, want valid %v", tt.sections, err, tt.valid)
+		}
+	}
+}
+
+func TestFilterParseErrors(t *testing.T) {
+	decklist := "1 Sol Ring (c21) 263\nnot a card\n\nMaybeboard\nnot a card either"
+	_, parseErrors := archidektParser{}.Parse(decklist, ParseOptions{})
+	parseErrors = append(parseErrors, &LineError{Err: errors.New("whole decklist")})
+
+	tests := []struct {
+		sections []string
+		want     []int
+	}{
+		{nil, []int{2, 0}},
+		{[]string{"maybeboard"}, []int{5, 0}},
+		{[]string{"all"}, []int{2, 5, 0}},
+	}
+	for _, tt := range tests {
+		var lines []int
+		for _, err := range filterParseErrors(parseErrors, ParseSections(tt.sections)) {
+			lines = append(lines, err.(*LineError).Line)
+		}
+		if !reflect.DeepEqual(lines, tt.want) {
+			t.Errorf("sections %v: error lines = %v, want %v", tt.sections, lines, tt.want)
+		}
+	}
+}
+
+func TestSectionHeader(t *testing.T) {
+	tests := []struct {
+		line    string
+		section string // empty when the line isn't a header
+	}{
+		{"Sideboard", SectionSideboard},
+		{"SIDEBOARD:", SectionSideboard},
+		{"// Commander", SectionCommander},
+		{"Commanders", SectionCommander},
+		{"Commanders:", SectionCommander},
+		{"Main Deck", SectionMainboard},
+		{"Token", SectionTokens},
+		{"Tokens (3)", SectionTokens},
+		{"About", "about"},
+		{"Ramp:", "ramp"},
+		{"Ramp", ""},
+		{"1 Sol Ring", ""},
+	}
+	for _, tt := range tests {
+		section, ok := sectionHeader(tt.line)
+		if ok != (tt.section != "") || section != tt.section {
+			t.Errorf("sectionHeader(%q) = %q, %v, want %q", tt.line, section, ok, tt.section)
+		}
+	}
+}
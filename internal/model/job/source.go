@@ -0,0 +1,79 @@
+package job
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CardSource looks up card data for decklist entries. Cards a source doesn't have
+// are reported as an *APIError with status 404.
+type CardSource interface {
+	// Card looks up the printing named by entry. Names are matched fuzzily.
+	Card(entry DeckEntry) (Card, error)
+	// Collection looks up many cards at once, returning the cards found and the identifiers that matched nothing
+	Collection(ids []CardIdentifier) ([]Card, []CardIdentifier, error)
+	// Printings returns the paper printings matching query, in the order it asks for
+	Printings(query PrintingQuery) ([]Card, error)
+	// SetExists reports whether code is a known set code
+	SetExists(code string) (bool, error)
+	// SuggestName returns the card name closest to name, or "" when nothing is close
+	SuggestName(name string) (string, error)
+	// ImageURIs returns the URIs of the card's face images, keyed "front" and "back"
+	ImageURIs(card *Card) map[string]string
+	// BackURL returns the URI of the generic back printed behind cards without a back face
+	BackURL() string
+	// ImageHosts returns the hosts, with any port, that card images and backs are downloaded from
+	ImageHosts() []string
+}
+
+// CardIdentifier identifies a card in a Collection lookup: by Scryfall ID, MTGO
+// catalog ID, set and collector number, or name within an optional set
+type CardIdentifier struct {
+	ID              string `json:"id,omitempty"`
+	MTGOID          int    `json:"mtgo_id,omitempty"`
+	Name            string `json:"name,omitempty"`
+	Set             string `json:"set,omitempty"`
+	CollectorNumber string `json:"collector_number,omitempty"`
+}
+
+// PrintingQuery selects the paper printings of a card
+type PrintingQuery struct {
+	OracleID  string
+	Set       string   // only printings from this set, when given
+	Sets      []string // only printings from one of these sets, when given
+	Order     string   // "released" or "usd"
+	Ascending bool
+}
+
+var (
+	cardSource  CardSource = NewScryfall(DefaultScryfallURL, nil)
+	imageClient            = &http.Client{Timeout: 60 * time.Second}
+	sourceMutex sync.RWMutex
+)
+
+// SetCardSource changes where jobs look up cards
+func SetCardSource(source CardSource) {
+	sourceMutex.Lock()
+	defer sourceMutex.Unlock()
+	cardSource = source
+}
+
+// SetImageClient changes the HTTP client card images are downloaded with
+func SetImageClient(client *http.Client) {
+	sourceMutex.Lock()
+	defer sourceMutex.Unlock()
+	imageClient = client
+}
+
+func getCardSource() CardSource {
+	sourceMutex.RLock()
+	defer sourceMutex.RUnlock()
+	return cardSource
+}
+
+func getImageClient() *http.Client {
+	sourceMutex.RLock()
+	defer sourceMutex.RUnlock()
+	return imageClient
+}
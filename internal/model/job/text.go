@@ -0,0 +1,212 @@
+package job
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/signintech/gopdf"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// RenderMode selects how card fronts are drawn
+type RenderMode string
+
+const (
+	RenderImage RenderMode = "image" // Scryfall card images
+	RenderText  RenderMode = "text"  // text proxies drawn from the card data, no artwork
+)
+
+// ParseRenderMode validates a render mode from the submit API. An empty mode selects RenderImage.
+func ParseRenderMode(mode string) (RenderMode, error) {
+	switch RenderMode(strings.ToLower(strings.TrimSpace(mode))) {
+	case "", RenderImage:
+		return RenderImage, nil
+	case RenderText:
+		return RenderText, nil
+	}
+	return "", fmt.Errorf("unknown render mode %q", mode)
+}
+
+// textFont is the font family used for text drawn on the page
+const textFont = "goregular"
+
+// addTextFont embeds the font used for text drawn on the page
+func addTextFont(pdf *gopdf.GoPdf) error {
+	if err := pdf.AddTTFFontData(textFont, goregular.TTF); err != nil {
+		return fmt.Errorf("failed to load font: %w", err)
+	}
+	return nil
+}
+
+// Text proxy sizes in points for a 180pt wide card, scaled to the layout's card size
+const (
+	textCardPadding  = 8
+	textCardNameSize = 9
+	textCardTypeSize = 7.5
+	textCardBodySize = 7
+	textCardMinSize  = 4
+	textCardFootSize = 5
+)
+
+// cardText is the printed text of one card face
+type cardText struct {
+	name, manaCost, typeLine, oracleText, stats string
+}
+
+// faceTexts returns the faces drawn on one side of a text proxy. A double-faced
+// card shows only the requested face, while split, adventure and flip cards
+// show every face on one card as they are printed.
+func faceTexts(card *Card, face string) []cardText {
+	if result, ok := meldResult(card); ok && face == "back" {
+		return []cardText{{name: result.Name, typeLine: result.TypeLine}}
+	}
+	if len(card.CardFaces) == 0 {
+		return []cardText{{card.Name, card.ManaCost, card.TypeLine, card.OracleText, statsText(card.Power, card.Toughness, card.Loyalty)}}
+	}
+
+	faces := card.CardFaces
+	if _, doubleFaced := card.ImageURIs["back"]; doubleFaced {
+		if face == "back" && len(faces) > 1 {
+			faces = faces[1:2]
+		} else {
+			faces = faces[:1]
+		}
+	}
+
+	texts := make([]cardText, len(faces))
+	for i, f := range faces {
+		texts[i] = cardText{f.Name, f.ManaCost, f.TypeLine, f.OracleText, statsText(f.Power, f.Toughness, f.Loyalty)}
+	}
+	return texts
+}
+
+// statsText formats power/toughness or starting loyalty
+func statsText(power, toughness, loyalty string) string {
+	switch {
+	case power != "" || toughness != "":
+		return power + "/" + toughness
+	case loyalty != "":
+		return "Loyalty " + loyalty
+	}
+	return ""
+}
+
+// drawTextCard fills a slot with a text proxy of one face of card, drawn from its
+// Scryfall data. Used for text-only jobs and in place of images that fail to load.
+func (l Layout) drawTextCard(pdf *gopdf.GoPdf, slot int, card *Card, face string) {
+	x, y := l.Slot(slot)
+	scale := l.CardW / 180
+	pad := textCardPadding * scale
+
+	pdf.SetFillColor(255, 255, 255)
+	pdf.SetStrokeColor(0, 0, 0)
+	pdf.SetLineWidth(scale)
+	pdf.Rectangle(x, y, x+l.CardW, y+l.CardH, "FD", 0, 0)
+
+	// Set and collector number along the bottom, so the printing can be identified
+	footSize := textCardFootSize * scale
+	footer := strings.ToUpper(card.Set)
+	if card.CollectorNumber != "" {
+		footer += " #" + card.CollectorNumber
+	}
+	drawTextLine(pdf, footer, footSize, x+pad, y+l.CardH-pad-footSize, l.CardW-2*pad, gopdf.Left)
+
+	texts := faceTexts(card, face)
+	top := y + pad
+	height := (l.CardH - 2*pad - 1.5*footSize) / float64(len(texts))
+	for i, text := range texts {
+		if i > 0 {
+			pdf.SetLineWidth(0.5 * scale)
+			pdf.Line(x+pad, top, x+l.CardW-pad, top)
+		}
+		drawFaceText(pdf, text, x+pad, top, l.CardW-2*pad, height, scale)
+		top += height
+	}
+}
+
+// drawFaceText draws one face of a text proxy into the box at x, top. The rules
+// text shrinks until it fits above the power/toughness or loyalty.
+func drawFaceText(pdf *gopdf.GoPdf, text cardText, x, top, width, height, scale float64) {
+	nameSize := textCardNameSize * scale
+	typeSize := textCardTypeSize * scale
+	bodySize := textCardBodySize * scale
+	bottom := top + height
+
+	manaWidth := 0.0
+	if text.manaCost != "" && pdf.SetFont(textFont, "", nameSize) == nil {
+		if w, err := pdf.MeasureTextWidth(text.manaCost); err == nil {
+			manaWidth = min(w, width/2)
+		}
+		drawTextLine(pdf, text.manaCost, nameSize, x+width-manaWidth, top+nameSize*0.2, manaWidth, gopdf.Right)
+	}
+	top = drawTextBlock(pdf, text.name, nameSize, x, top, width-manaWidth-2*scale)
+
+	pdf.SetLineWidth(0.5 * scale)
+	pdf.Line(x, top+2*scale, x+width, top+2*scale)
+	top = drawTextBlock(pdf, text.typeLine, typeSize, x, top+4*scale, width)
+	pdf.Line(x, top+2*scale, x+width, top+2*scale)
+	top += 5 * scale
+
+	if text.stats != "" {
+		bottom -= nameSize * 1.4
+		drawTextLine(pdf, text.stats, nameSize, x, bottom+nameSize*0.2, width, gopdf.Right)
+	}
+
+	// Oracle text paragraphs are separated by newlines
+	paragraphs := strings.Split(text.oracleText, "\n")
+	for size := bodySize; size >= textCardMinSize*scale; size -= 0.5 * scale {
+		if textHeight(pdf, paragraphs, size, width) <= bottom-top || size-0.5*scale < textCardMinSize*scale {
+			for _, paragraph := range paragraphs {
+				top = drawTextBlock(pdf, paragraph, size, x, top, width) + size*0.3
+			}
+			return
+		}
+	}
+}
+
+// textHeight returns the height of paragraphs word-wrapped at width
+func textHeight(pdf *gopdf.GoPdf, paragraphs []string, size, width float64) float64 {
+	if err := pdf.SetFont(textFont, "", size); err != nil {
+		return 0
+	}
+	height := 0.0
+	for _, paragraph := range paragraphs {
+		lines, err := pdf.SplitTextWithWordWrap(paragraph, width)
+		if err != nil {
+			lines = []string{paragraph}
+		}
+		height += float64(len(lines))*size*1.2 + size*0.3
+	}
+	return height
+}
+
+// drawTextBlock word-wraps left-aligned text into a column of the given width
+// starting at top, and returns the position below the last line
+func drawTextBlock(pdf *gopdf.GoPdf, text string, size, x, top, width float64) float64 {
+	if strings.TrimSpace(text) == "" {
+		return top
+	}
+	if err := pdf.SetFont(textFont, "", size); err != nil {
+		return top
+	}
+
+	lines, err := pdf.SplitTextWithWordWrap(text, width)
+	if err != nil {
+		lines = []string{text}
+	}
+	for _, line := range lines {
+		drawTextLine(pdf, line, size, x, top, width, gopdf.Left)
+		top += size * 1.2
+	}
+	return top
+}
+
+// drawTextLine draws a single line of text aligned within a cell of the given width
+func drawTextLine(pdf *gopdf.GoPdf, text string, size, x, top, width float64, align int) {
+	if text == "" || pdf.SetFont(textFont, "", size) != nil {
+		return
+	}
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetXY(x, top)
+	pdf.CellWithOption(&gopdf.Rect{W: width, H: size * 1.2}, text, gopdf.CellOption{Align: align})
+}
@@ -0,0 +1,54 @@
+package job
+
+import "log"
+
+// Validation is the outcome of checking a decklist without printing it
+type Validation struct {
+	Format      DecklistFormat `json:"format"`
+	Valid       bool           `json:"valid"`       // every line resolved to a card
+	Cards       []ResolvedCard `json:"cards"`       // printings the decklist lines resolved to
+	Diagnostics []Diagnostic   `json:"diagnostics"` // lines that couldn't be resolved
+}
+
+// ValidateDecklist parses decklist and looks its cards up on Scryfall the way a job
+// would, without fetching images or generating a PDF
+func ValidateDecklist(decklist string, options JobOptions) (*Validation, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	format, err := ParseDecklistFormat(options.Format)
+	if err != nil {
+		return nil, err
+	}
+	printing, err := options.ResolvePrintingPreference()
+	if err != nil {
+		return nil, err
+	}
+
+	format, entries, parseErrors := ParseDecklist(decklist, format, ParseOptions{CSVColumns: options.CSVColumns})
+	log.Printf("Validation: Parsed %d card lines as %s", len(entries), format)
+
+	sections := ParseSections(options.Sections)
+	if err := sections.check(entries, parseErrors); err != nil {
+		return nil, err
+	}
+
+	validation := &Validation{
+		Format:      format,
+		Cards:       []ResolvedCard{},
+		Diagnostics: []Diagnostic{},
+	}
+	for _, err := range filterParseErrors(parseErrors, sections) {
+		validation.Diagnostics = append(validation.Diagnostics, parseDiagnostic(err))
+	}
+
+	entries = filterSections(entries, sections)
+	if len(entries) > 0 {
+		_, resolved, diagnostics := lookupCards("Validation", entries, printing)
+		validation.Cards = append(validation.Cards, resolved...)
+		validation.Diagnostics = append(validation.Diagnostics, diagnostics...)
+	}
+	sortDiagnostics(validation.Diagnostics)
+	validation.Valid = len(validation.Diagnostics) == 0
+	return validation, nil
+}